@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Supported GDP estimators
+const (
+	GDPEstimatorSeeded    = "seeded"
+	GDPEstimatorFixed     = "fixed"
+	GDPEstimatorPerCapita = "per_capita"
+	GDPEstimatorRandom    = "random"
+)
+
+// DefaultGDPMultiplier is the multiplier used by the fixed estimator when none is configured
+const DefaultGDPMultiplier = 1500.0
+
+// GDPConfig selects how estimated_gdp is computed during a refresh
+type GDPConfig struct {
+	Estimator  string  // "seeded", "fixed", "per_capita" or "random"
+	Multiplier float64 // Used by the fixed estimator
+	Seed       string  // Extra salt mixed into the seeded estimator
+	Fallback   string  // Estimator used by per_capita when a country has no GDP per capita
+}
+
+// LoadGDPConfig reads the GDP estimation settings from environment variables
+func LoadGDPConfig() (GDPConfig, error) {
+	cfg := GDPConfig{
+		Estimator:  getEnvDefault("GDP_ESTIMATOR", GDPEstimatorSeeded),
+		Multiplier: DefaultGDPMultiplier,
+		Seed:       os.Getenv("GDP_SEED"),
+		Fallback:   getEnvDefault("GDP_FALLBACK_ESTIMATOR", GDPEstimatorSeeded),
+	}
+
+	if raw := os.Getenv("GDP_MULTIPLIER"); raw != "" {
+		multiplier, err := strconv.ParseFloat(raw, 64)
+		if err != nil || multiplier <= 0 {
+			return cfg, fmt.Errorf("GDP_MULTIPLIER must be a positive number, got %q", raw)
+		}
+		cfg.Multiplier = multiplier
+	}
+
+	switch cfg.Estimator {
+	case GDPEstimatorSeeded, GDPEstimatorFixed, GDPEstimatorPerCapita, GDPEstimatorRandom:
+	default:
+		return cfg, fmt.Errorf("unknown GDP_ESTIMATOR %q", cfg.Estimator)
+	}
+	switch cfg.Fallback {
+	case GDPEstimatorSeeded, GDPEstimatorFixed, GDPEstimatorRandom:
+	default:
+		return cfg, fmt.Errorf("unsupported GDP_FALLBACK_ESTIMATOR %q", cfg.Fallback)
+	}
+
+	return cfg, nil
+}
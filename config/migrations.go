@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// preMigrationStatements adjust the existing schema before AutoMigrate runs.
+// Each statement must be idempotent and must work before the tables exist.
+var preMigrationStatements = []string{
+	// idx_countries_name_key replaces the case-sensitive unique constraint countries.name used to have,
+	// under the name current GORM versions give it or the one older versions did
+	`ALTER TABLE IF EXISTS countries DROP CONSTRAINT IF EXISTS uni_countries_name`,
+	`ALTER TABLE IF EXISTS countries DROP CONSTRAINT IF EXISTS countries_name_key`,
+}
+
+// schemaStatements create the indexes and extensions that GORM's AutoMigrate can't express.
+// Each statement must be idempotent, since they run on every startup.
+var schemaStatements = []string{
+	// Refreshes upsert countries on their case-insensitive name
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_countries_name_key ON countries (LOWER(name))`,
+
+	// Search matches names with accents, case and punctuation removed, by prefix or by trigram similarity.
+	// unaccent isn't IMMUTABLE, so it is wrapped with a fixed dictionary to be usable in indexes.
+	`CREATE EXTENSION IF NOT EXISTS unaccent`,
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE OR REPLACE FUNCTION country_search_key(text) RETURNS text AS $$
+		SELECT btrim(regexp_replace(lower(public.unaccent('public.unaccent'::regdictionary, $1)), '[[:punct:][:space:]]+', ' ', 'g'))
+	$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE`,
+	// Alternate names are indexed as their keys joined by " | ", which no key contains,
+	// so a substring of the joined keys that has no "|" is a substring of one of them
+	`CREATE OR REPLACE FUNCTION country_alt_search_key(jsonb) RETURNS text AS $$
+		SELECT string_agg(country_search_key(alt), ' | ') FROM jsonb_array_elements_text($1) AS alt
+	$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE`,
+	`CREATE INDEX IF NOT EXISTS idx_countries_name_trgm ON countries USING gin (country_search_key(name) gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_countries_capital_trgm ON countries USING gin (country_search_key(capital) gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_countries_alt_names_trgm ON countries USING gin (country_alt_search_key(alt_names) gin_trgm_ops)`,
+}
+
+// ApplySchemaMigrations runs the schema statements that precede AutoMigrate
+func ApplySchemaMigrations(db *gorm.DB) error {
+	return execSchemaStatements(db, preMigrationStatements)
+}
+
+// ApplySchemaExtras runs the schema statements that follow AutoMigrate
+func ApplySchemaExtras(db *gorm.DB) error {
+	return execSchemaStatements(db, schemaStatements)
+}
+
+// execSchemaStatements runs statements in order, stopping at the first that fails
+func execSchemaStatements(db *gorm.DB, statements []string) error {
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to apply schema statement %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
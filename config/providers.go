@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// Supported data provider kinds
+const (
+	ProviderRestCountries = "restcountries"
+	ProviderERAPI         = "erapi"
+	ProviderFile          = "file"
+)
+
+// Default upstream endpoints
+const (
+	DefaultCountriesURL = "https://restcountries.com/v2/all?fields=name,capital,region,population,flag,currencies,altSpellings,nativeName"
+	DefaultRatesURL     = "https://open.er-api.com/v6/latest/USD"
+)
+
+// ProviderConfig selects where country and exchange rate data is fetched from
+type ProviderConfig struct {
+	CountryProvider string // "restcountries" or "file"
+	CountriesURL    string // Used by the restcountries provider, e.g. an internal mirror
+	CountriesFile   string // Used by the file provider
+	RateProvider    string // "erapi" or "file"
+	RatesURL        string // Used by the erapi provider; any USD-based endpoint returning a "rates" object works
+	RatesFile       string // Used by the file provider
+}
+
+// LoadProviderConfig reads the data provider settings from environment variables
+func LoadProviderConfig() (ProviderConfig, error) {
+	cfg := ProviderConfig{
+		CountryProvider: getEnvDefault("COUNTRY_PROVIDER", ProviderRestCountries),
+		CountriesURL:    getEnvDefault("COUNTRY_PROVIDER_URL", DefaultCountriesURL),
+		CountriesFile:   os.Getenv("COUNTRY_PROVIDER_FILE"),
+		RateProvider:    getEnvDefault("RATE_PROVIDER", ProviderERAPI),
+		RatesURL:        getEnvDefault("RATE_PROVIDER_URL", DefaultRatesURL),
+		RatesFile:       os.Getenv("RATE_PROVIDER_FILE"),
+	}
+
+	switch cfg.CountryProvider {
+	case ProviderRestCountries:
+	case ProviderFile:
+		if cfg.CountriesFile == "" {
+			return cfg, fmt.Errorf("COUNTRY_PROVIDER_FILE is required when COUNTRY_PROVIDER is %q", ProviderFile)
+		}
+	default:
+		return cfg, fmt.Errorf("unknown COUNTRY_PROVIDER %q", cfg.CountryProvider)
+	}
+
+	switch cfg.RateProvider {
+	case ProviderERAPI:
+	case ProviderFile:
+		if cfg.RatesFile == "" {
+			return cfg, fmt.Errorf("RATE_PROVIDER_FILE is required when RATE_PROVIDER is %q", ProviderFile)
+		}
+	default:
+		return cfg, fmt.Errorf("unknown RATE_PROVIDER %q", cfg.RateProvider)
+	}
+
+	return cfg, nil
+}
+
+// getEnvDefault returns the value of an environment variable, or fallback if it is unset or empty
+func getEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// HTTPConfig controls how outbound requests to data providers are made
+type HTTPConfig struct {
+	Mode        string // "live", "record" or "replay"
+	FixturesDir string // Where responses are recorded to and replayed from
+}
+
+// LoadHTTPConfig reads the outbound HTTP settings from environment variables
+func LoadHTTPConfig() HTTPConfig {
+	return HTTPConfig{
+		Mode:        getEnvDefault("HTTP_MODE", "live"),
+		FixturesDir: getEnvDefault("HTTP_FIXTURES_DIR", "fixtures"),
+	}
+}
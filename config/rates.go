@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultRateMaxAge is how old a rate may be before conversions refuse to use it
+const DefaultRateMaxAge = 48 * time.Hour
+
+// RateConfig holds the settings for using stored exchange rates
+type RateConfig struct {
+	// MaxAge is how long before the conversion time a rate may have been fetched; 0 accepts any age
+	MaxAge time.Duration
+}
+
+// LoadRateConfig reads the exchange rate settings from environment variables
+func LoadRateConfig() (RateConfig, error) {
+	cfg := RateConfig{MaxAge: DefaultRateMaxAge}
+
+	if maxAge := os.Getenv("RATE_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid RATE_MAX_AGE %q: %w", maxAge, err)
+		}
+		if d < 0 {
+			return cfg, fmt.Errorf("RATE_MAX_AGE must not be negative, got %s", maxAge)
+		}
+		cfg.MaxAge = d
+	}
+	return cfg, nil
+}
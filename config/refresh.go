@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Policies for countries that disappear from the upstream feed
+const (
+	ReconcileKeep       = "keep"        // Keep the row and flag it as stale
+	ReconcileSoftDelete = "soft_delete" // Soft-delete the row
+	ReconcileHardDelete = "hard_delete" // Permanently delete the row
+)
+
+// RefreshConfig holds the settings that control how a refresh merges upstream data
+type RefreshConfig struct {
+	ReconcilePolicy string
+	// ResurrectDeleted lets a refresh restore countries that were deleted through the API.
+	// Countries soft-deleted by reconciliation are always restored when they reappear upstream.
+	ResurrectDeleted bool
+}
+
+// LoadRefreshConfig reads the refresh settings from environment variables
+func LoadRefreshConfig() (RefreshConfig, error) {
+	cfg := RefreshConfig{
+		ReconcilePolicy: getEnvDefault("RECONCILE_POLICY", ReconcileKeep),
+	}
+
+	if resurrect := os.Getenv("RESURRECT_DELETED"); resurrect != "" {
+		b, err := strconv.ParseBool(resurrect)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid RESURRECT_DELETED %q: %w", resurrect, err)
+		}
+		cfg.ResurrectDeleted = b
+	}
+
+	switch cfg.ReconcilePolicy {
+	case ReconcileKeep, ReconcileSoftDelete, ReconcileHardDelete:
+	default:
+		return cfg, fmt.Errorf("unknown RECONCILE_POLICY %q", cfg.ReconcilePolicy)
+	}
+	return cfg, nil
+}
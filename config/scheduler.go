@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// SchedulerConfig holds the settings for the background refresh scheduler
+type SchedulerConfig struct {
+	CronExpr   string        // Standard 5-field cron expression, e.g. "0 */6 * * *"
+	Interval   time.Duration // Fixed interval between runs, used when CronExpr is empty
+	InstanceID string        // Identifies this replica in status reports
+}
+
+// Enabled reports whether a refresh schedule has been configured
+func (c SchedulerConfig) Enabled() bool {
+	return c.CronExpr != "" || c.Interval > 0
+}
+
+// LoadSchedulerConfig reads the scheduler settings from environment variables
+func LoadSchedulerConfig() (SchedulerConfig, error) {
+	cfg := SchedulerConfig{
+		CronExpr:   os.Getenv("REFRESH_CRON"),
+		InstanceID: InstanceID(),
+	}
+
+	if interval := os.Getenv("REFRESH_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid REFRESH_INTERVAL %q: %w", interval, err)
+		}
+		if d <= 0 {
+			return cfg, fmt.Errorf("REFRESH_INTERVAL must be positive, got %s", interval)
+		}
+		cfg.Interval = d
+	}
+
+	if cfg.CronExpr != "" && cfg.Interval > 0 {
+		return cfg, fmt.Errorf("REFRESH_CRON and REFRESH_INTERVAL are mutually exclusive")
+	}
+	return cfg, nil
+}
+
+// InstanceID returns the name this replica reports itself as, from INSTANCE_ID or the hostname
+func InstanceID() string {
+	if id := os.Getenv("INSTANCE_ID"); id != "" {
+		return id
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "unknown"
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditController handles HTTP requests related to the audit log
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+// NewAuditController creates a new AuditController
+func NewAuditController(as *services.AuditService) *AuditController {
+	return &AuditController{auditService: as}
+}
+
+// GetAuditEvents handles the GET /audit endpoint
+func (ctrl *AuditController) GetAuditEvents(c *gin.Context) {
+	from, ok := parseTimeQuery(c, "from", false)
+	if !ok {
+		return
+	}
+	to, ok := parseTimeQuery(c, "to", true)
+	if !ok {
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			utils.HandleBadRequestError(c, map[string]string{"limit": "must be an integer between 1 and " + strconv.Itoa(maxHistoryLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := ctrl.auditService.GetEvents(services.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		From:   from,
+		To:     to,
+		Limit:  limit,
+	})
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get audit events")
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// auditContext identifies who made a request: the X-User header if set, otherwise a fingerprint of
+// the X-API-Key header, otherwise "anonymous". API keys themselves are never stored.
+func auditContext(c *gin.Context) services.AuditContext {
+	actor := "anonymous"
+	if user := strings.TrimSpace(c.GetHeader("X-User")); user != "" {
+		actor = user
+	} else if key := c.GetHeader("X-API-Key"); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		actor = "api-key:" + hex.EncodeToString(sum[:])[:12]
+	}
+	return services.AuditContext{Actor: actor, RequestID: utils.RequestID(c)}
+}
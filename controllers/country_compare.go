@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds on the number of countries GET /countries/compare accepts
+const (
+	minCompareNames = 2
+	maxCompareNames = 10
+)
+
+// CompareCountries handles the GET /countries/compare endpoint
+func (ctrl *CountryController) CompareCountries(c *gin.Context) {
+	names := splitList(c.Query("names"))
+	if len(names) < minCompareNames || len(names) > maxCompareNames {
+		utils.HandleBadRequestError(c, map[string]string{
+			"names": "must list between " + strconv.Itoa(minCompareNames) + " and " + strconv.Itoa(maxCompareNames) + " comma-separated country names",
+		})
+		return
+	}
+
+	comparison, err := ctrl.countryService.CompareCountries(names)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to compare countries")
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
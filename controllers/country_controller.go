@@ -1,10 +1,15 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"reflect"
 	"strings"
+	"time"
 
 	"stage-2/models"
 	"stage-2/services"
@@ -17,51 +22,89 @@ import (
 
 // CountryController handles HTTP requests related to countries
 type CountryController struct {
-	countryService *services.CountryService
-	statusService  *services.StatusService
+	countryService    *services.CountryService
+	statusService     *services.StatusService
+	refreshJobService *services.RefreshJobService
 }
 
 // NewCountryController creates a new CountryController
-func NewCountryController(cs *services.CountryService, ss *services.StatusService) *CountryController {
-	return &CountryController{countryService: cs, statusService: ss}
+func NewCountryController(cs *services.CountryService, ss *services.StatusService, js *services.RefreshJobService) *CountryController {
+	return &CountryController{countryService: cs, statusService: ss, refreshJobService: js}
 }
 
-// RefreshCountries handles the POST /countries/refresh endpoint
+// RefreshCountries handles the POST /countries/refresh endpoint by queueing a refresh job
 func (ctrl *CountryController) RefreshCountries(c *gin.Context) {
-	total, lastRefreshed, err := ctrl.countryService.RefreshCountries()
+	job, err := ctrl.refreshJobService.EnqueueRefresh(auditContext(c))
 	if err != nil {
-		// ExternalAPIError is handled by middleware
-		utils.HandleInternalServerError(c, err, "failed to refresh countries")
+		utils.HandleInternalServerError(c, err, "failed to queue refresh job")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":           "Countries refreshed successfully",
-		"total_countries":   total,
-		"last_refreshed_at": lastRefreshed.Format("2006-01-02T15:04:05Z"),
+	statusURL := fmt.Sprintf("/refresh-jobs/%d", job.ID)
+	c.Header("Location", statusURL)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Refresh job queued",
+		"job_id":     job.ID,
+		"state":      job.State,
+		"status_url": statusURL,
 	})
 }
 
 // GetCountries handles the GET /countries endpoint
 func (ctrl *CountryController) GetCountries(c *gin.Context) {
-	region := c.Query("region")
-	currency := c.Query("currency")
-	sort := c.Query("sort") // e.g., gdp_desc, name_asc, population_desc
+	filter, ok := parseCountryFilter(c)
+	if !ok {
+		return
+	}
+	projection, ok := parseCountryProjection(c)
+	if !ok {
+		return
+	}
+	format, ok := negotiateExportFormat(c)
+	if !ok {
+		return
+	}
+	// Exports contain every matching country, so they aren't paginated
+	if format != "" {
+		ctrl.exportCountries(c, filter, projection, format)
+		return
+	}
+	page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
 
-	countries, err := ctrl.countryService.GetCountries(region, currency, sort)
+	result, err := ctrl.countryService.GetCountries(filter, page, projection)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			utils.HandleBadRequestError(c, map[string]string{"cursor": "is invalid or was issued for a different sort order"})
+			return
+		}
 		utils.HandleInternalServerError(c, err, "failed to get countries")
 		return
 	}
 
-	c.JSON(http.StatusOK, countries)
+	data, err := projectCountries(result.Countries, projection)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to encode countries")
+		return
+	}
+	writePage(c, data, page, result.Total, result.NextCursor)
 }
 
 // GetCountryByName handles the GET /countries/:name endpoint
 func (ctrl *CountryController) GetCountryByName(c *gin.Context) {
 	name := c.Param("name")
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	projection, ok := parseCountryProjection(c)
+	if !ok {
+		return
+	}
 
-	country, err := ctrl.countryService.GetCountryByName(name)
+	country, err := ctrl.countryService.GetCountryByName(name, asOf, projection)
 	if err != nil {
 		utils.HandleInternalServerError(c, err, "failed to get country by name")
 		return
@@ -71,14 +114,215 @@ func (ctrl *CountryController) GetCountryByName(c *gin.Context) {
 		return
 	}
 
+	data, err := projectCountry(country, projection)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to encode country")
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// writableCountryFields are the JSON fields clients may set when creating or updating a country
+var writableCountryFields = []string{"name", "capital", "region", "population", "currency_code", "exchange_rate", "estimated_gdp", "flag_url"}
+
+// CreateCountry handles the POST /countries endpoint
+func (ctrl *CountryController) CreateCountry(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.HandleBadRequestError(c, map[string]string{"body": "could not be read"})
+		return
+	}
+	input, ok := decodeCountry(c, body)
+	if !ok {
+		return
+	}
+
+	country, err := ctrl.countryService.CreateCountry(auditContext(c), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCountryExists):
+			utils.HandleConflictError(c, "Country already exists")
+		case errors.Is(err, services.ErrCountryInTrash):
+			utils.HandleConflictError(c, "Country is in the trash; restore or purge it first")
+		default:
+			utils.HandleInternalServerError(c, err, "failed to create country")
+		}
+		return
+	}
+
+	c.Header("Location", "/countries/"+url.PathEscape(country.Name))
+	c.JSON(http.StatusCreated, country)
+}
+
+// UpdateCountry handles the PUT /countries/:name endpoint by replacing every writable field
+func (ctrl *CountryController) UpdateCountry(c *gin.Context) {
+	name := c.Param("name")
+
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.HandleBadRequestError(c, map[string]string{"body": "could not be read"})
+		return
+	}
+	input, ok := decodeCountry(c, body)
+	if !ok {
+		return
+	}
+
+	ctrl.saveCountry(c, name, input)
+}
+
+// PatchCountry handles the PATCH /countries/:name endpoint by applying a JSON Merge Patch (RFC 7386)
+func (ctrl *CountryController) PatchCountry(c *gin.Context) {
+	name := c.Param("name")
+
+	if ct := c.ContentType(); ct != "" && ct != "application/merge-patch+json" && ct != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, utils.NewAPIError("Unsupported media type", "use application/merge-patch+json"))
+		return
+	}
+	patch, err := c.GetRawData()
+	if err != nil {
+		utils.HandleBadRequestError(c, map[string]string{"body": "could not be read"})
+		return
+	}
+	var patchFields map[string]interface{}
+	if err := json.Unmarshal(patch, &patchFields); err != nil || patchFields == nil {
+		utils.HandleBadRequestError(c, map[string]string{"body": "must be a JSON object"})
+		return
+	}
+	if errs := checkWritableFields(patchFields); errs != nil {
+		utils.HandleBadRequestError(c, errs)
+		return
+	}
+
+	current, err := ctrl.countryService.GetCountryByName(name, nil, services.CountryProjection{})
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get country by name")
+		return
+	}
+	if current == nil {
+		utils.HandleNotFoundError(c, "Country")
+		return
+	}
+
+	// Patch only the writable fields, so the result decodes like a PUT body. The derived fields are left out,
+	// so they only count as set by the client when the patch sets them.
+	doc := map[string]interface{}{
+		"name":          current.Name,
+		"capital":       current.Capital,
+		"region":        current.Region,
+		"population":    current.Population,
+		"currency_code": current.CurrencyCode,
+		"flag_url":      current.FlagURL,
+	}
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to encode country")
+		return
+	}
+	patched, err := utils.ApplyMergePatch(docJSON, patch)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to apply merge patch")
+		return
+	}
+	input, ok := decodeCountry(c, patched)
+	if !ok {
+		return
+	}
+
+	// Derived fields the patch doesn't mention keep their values unless their inputs change;
+	// a null derives them again
+	var keep []string
+	for _, field := range []string{"exchange_rate", "estimated_gdp"} {
+		if _, ok := patchFields[field]; !ok {
+			keep = append(keep, field)
+		}
+	}
+	ctrl.saveCountry(c, name, input, keep...)
+}
+
+// saveCountry writes a PUT or PATCH update of the named country and the response.
+// keep lists the derived fields to keep while their inputs are unchanged.
+func (ctrl *CountryController) saveCountry(c *gin.Context, name string, input *models.Country, keep ...string) {
+	country, err := ctrl.countryService.UpdateCountry(auditContext(c), name, input, keep...)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.HandleNotFoundError(c, "Country")
+		case errors.Is(err, services.ErrCountryExists):
+			utils.HandleConflictError(c, "Country already exists")
+		case errors.Is(err, services.ErrCountryInTrash):
+			utils.HandleConflictError(c, "Country is in the trash; restore or purge it first")
+		default:
+			utils.HandleInternalServerError(c, err, "failed to update country")
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, country)
 }
 
+// decodeCountry decodes and validates a country from a request body.
+// It writes a 400 response and returns false if the body is invalid.
+func decodeCountry(c *gin.Context, body []byte) (*models.Country, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		utils.HandleBadRequestError(c, map[string]string{"body": "must be a JSON object"})
+		return nil, false
+	}
+	if errs := checkWritableFields(fields); errs != nil {
+		utils.HandleBadRequestError(c, errs)
+		return nil, false
+	}
+
+	var country models.Country
+	if err := json.Unmarshal(body, &country); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			utils.HandleBadRequestError(c, map[string]string{typeErr.Field: "must be a valid " + typeErr.Type.String()})
+			return nil, false
+		}
+		utils.HandleBadRequestError(c, map[string]string{"body": "must be a JSON object"})
+		return nil, false
+	}
+	country.Name = strings.TrimSpace(country.Name)
+	if country.CurrencyCode != nil {
+		code := strings.ToUpper(*country.CurrencyCode)
+		country.CurrencyCode = &code
+	}
+
+	if errs := validateCountry(&country); errs != nil {
+		utils.HandleBadRequestError(c, errs)
+		return nil, false
+	}
+	return &country, true
+}
+
+// checkWritableFields reports every field in a request body that clients can't set
+func checkWritableFields(fields map[string]interface{}) map[string]string {
+	errs := make(map[string]string)
+	for field := range fields {
+		writable := false
+		for _, allowed := range writableCountryFields {
+			if field == allowed {
+				writable = true
+				break
+			}
+		}
+		if !writable {
+			errs[field] = "cannot be set; writable fields are " + strings.Join(writableCountryFields, ", ")
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 // DeleteCountry handles the DELETE /countries/:name endpoint
 func (ctrl *CountryController) DeleteCountry(c *gin.Context) {
 	name := c.Param("name")
 
-	err := ctrl.countryService.DeleteCountry(name)
+	err := ctrl.countryService.DeleteCountry(auditContext(c), name)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.HandleNotFoundError(c, "Country")
@@ -91,6 +335,84 @@ func (ctrl *CountryController) DeleteCountry(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Country deleted successfully"})
 }
 
+// GetTrash handles the GET /countries/trash endpoint
+func (ctrl *CountryController) GetTrash(c *gin.Context) {
+	trash, err := ctrl.countryService.GetTrash()
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get deleted countries")
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// RestoreCountry handles the POST /countries/:name/restore endpoint
+func (ctrl *CountryController) RestoreCountry(c *gin.Context) {
+	name := c.Param("name")
+
+	country, err := ctrl.countryService.RestoreCountry(auditContext(c), name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.HandleNotFoundError(c, "Deleted country")
+			return
+		}
+		utils.HandleInternalServerError(c, err, "failed to restore country")
+		return
+	}
+
+	c.JSON(http.StatusOK, country)
+}
+
+// PurgeCountry handles the DELETE /countries/trash/:name endpoint
+func (ctrl *CountryController) PurgeCountry(c *gin.Context) {
+	name := c.Param("name")
+
+	err := ctrl.countryService.PurgeCountry(auditContext(c), name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.HandleNotFoundError(c, "Deleted country")
+			return
+		}
+		utils.HandleInternalServerError(c, err, "failed to purge country")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Country purged successfully"})
+}
+
+// overridesRequest is the body of PUT /countries/:name/overrides
+type overridesRequest struct {
+	Overrides map[string]interface{} `json:"overrides"`
+	Reason    *string                `json:"reason"`
+}
+
+// SetOverrides handles the PUT /countries/:name/overrides endpoint
+func (ctrl *CountryController) SetOverrides(c *gin.Context) {
+	name := c.Param("name")
+
+	var req overridesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Overrides == nil {
+		utils.HandleBadRequestError(c, map[string]string{"overrides": "is required and must be an object of field values"})
+		return
+	}
+	if errs := services.ValidateOverrides(req.Overrides); errs != nil {
+		utils.HandleBadRequestError(c, errs)
+		return
+	}
+
+	country, err := ctrl.countryService.SetOverrides(auditContext(c), name, req.Overrides, req.Reason)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.HandleNotFoundError(c, "Country")
+			return
+		}
+		utils.HandleInternalServerError(c, err, "failed to set country overrides")
+		return
+	}
+
+	c.JSON(http.StatusOK, country)
+}
+
 // ServeSummaryImage handles the GET /countries/image endpoint
 func (ctrl *CountryController) ServeSummaryImage(c *gin.Context) {
 	imagePath := utils.GetSummaryImagePath()
@@ -104,16 +426,59 @@ func (ctrl *CountryController) ServeSummaryImage(c *gin.Context) {
 	c.File(imagePath)
 }
 
-// validateCountry ensures the required fields for a country are present
+// parseAsOf reads the optional as_of query parameter; see parseTimeQuery
+func parseAsOf(c *gin.Context) (*time.Time, bool) {
+	return parseTimeQuery(c, "as_of", true)
+}
+
+// parseTimeQuery reads an optional query parameter as an RFC 3339 timestamp or a YYYY-MM-DD date.
+// A date means the start of that day in UTC, or its end if endOfDay is set.
+// It writes a 400 response and returns false if the value is invalid.
+func parseTimeQuery(c *gin.Context, key string, endOfDay bool) (*time.Time, bool) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		t = t.UTC()
+		return &t, true
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return &t, true
+	}
+	utils.HandleBadRequestError(c, map[string]string{key: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+	return nil, false
+}
+
+// validateCountry ensures the required fields for a country are present and its values are in range
 func validateCountry(country *models.Country) map[string]string {
 	validate := validator.New()
-	err := validate.Struct(country)
-	if err != nil {
-		validationErrors := make(map[string]string)
+	// Use the binding tags on models.Country and report fields by their JSON names
+	validate.SetTagName("binding")
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+
+	validationErrors := make(map[string]string)
+	if err := validate.Struct(country); err != nil {
 		for _, err := range err.(validator.ValidationErrors) {
-			fieldName := strings.ToLower(err.Field())
-			validationErrors[fieldName] = "is required"
+			validationErrors[err.Field()] = "is required"
 		}
+	}
+	if country.CurrencyCode != nil && !services.ValidCurrencyCode(*country.CurrencyCode) {
+		validationErrors["currency_code"] = "must be a 3-letter currency code"
+	}
+	if country.ExchangeRate != nil && *country.ExchangeRate <= 0 {
+		validationErrors["exchange_rate"] = "must be a positive number"
+	}
+	if country.EstimatedGDP != nil && *country.EstimatedGDP < 0 {
+		validationErrors["estimated_gdp"] = "must be a non-negative number"
+	}
+
+	if len(validationErrors) > 0 {
 		return validationErrors
 	}
 	return nil
@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"stage-2/models"
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// Export formats of GET /countries
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+	exportXLSX   = "xlsx"
+)
+
+// Content types of the export formats
+const (
+	mimeCSV    = "text/csv"
+	mimeNDJSON = "application/x-ndjson"
+	mimeXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// exportContentTypes maps each export format to its content type
+var exportContentTypes = map[string]string{
+	exportCSV:    mimeCSV,
+	exportNDJSON: mimeNDJSON,
+	exportXLSX:   mimeXLSX,
+}
+
+// exportFlushInterval is how many rows CSV and NDJSON exports write between flushes
+const exportFlushInterval = 100
+
+// xlsxSheet is the name of the worksheet in XLSX exports
+const xlsxSheet = "Countries"
+
+// negotiateExportFormat picks the response format of GET /countries from the format query parameter,
+// or else from the Accept header. It returns an empty format for JSON. It writes a 400 response and
+// returns false if format is unknown.
+func negotiateExportFormat(c *gin.Context) (string, bool) {
+	if format := c.Query("format"); format != "" {
+		if format == "json" {
+			return "", true
+		}
+		if _, ok := exportContentTypes[format]; !ok {
+			utils.HandleBadRequestError(c, map[string]string{"format": "must be one of json, csv, ndjson, xlsx"})
+			return "", false
+		}
+		return format, true
+	}
+
+	// JSON is offered first, so it wins for */* and for Accept headers that match nothing
+	switch c.NegotiateFormat(gin.MIMEJSON, mimeCSV, mimeNDJSON, mimeXLSX) {
+	case mimeCSV:
+		return exportCSV, true
+	case mimeNDJSON:
+		return exportNDJSON, true
+	case mimeXLSX:
+		return exportXLSX, true
+	}
+	return "", true
+}
+
+// exportCountries streams every country matching filter in the given format
+func (ctrl *CountryController) exportCountries(c *gin.Context, filter services.CountryFilter, projection services.CountryProjection, format string) {
+	if len(projection.Include) > 0 {
+		utils.HandleBadRequestError(c, map[string]string{"include": "is not supported for csv, ndjson and xlsx exports"})
+		return
+	}
+	columns, ok := exportColumns(c, filter, projection)
+	if !ok {
+		return
+	}
+	if filter.AsOf != nil {
+		projection.Fields = columns // NDJSON rows leave out the fields that aren't recorded too
+	}
+
+	if format == exportXLSX {
+		ctrl.exportXLSX(c, filter, projection, columns)
+		return
+	}
+
+	writeStreamedExport(c, format, columns, projection, func(fn func(*models.Country) error) error {
+		return ctrl.countryService.StreamCountries(filter, projection, fn)
+	})
+}
+
+// exportColumns returns the fields an export writes: the selected fields, or else every field.
+// With as_of, only the fields snapshots record can be exported, since the others would be empty rather
+// than historical. It writes a 400 response and returns false if another field is selected.
+func exportColumns(c *gin.Context, filter services.CountryFilter, projection services.CountryProjection) ([]string, bool) {
+	if filter.AsOf == nil {
+		if len(projection.Fields) == 0 {
+			return services.SelectableCountryFields, true
+		}
+		return projection.Fields, true
+	}
+
+	if len(projection.Fields) == 0 {
+		return services.AsOfCountryFields, true
+	}
+	for _, field := range projection.Fields {
+		if !slices.Contains(services.AsOfCountryFields, field) {
+			utils.HandleBadRequestError(c, map[string]string{
+				"fields": fmt.Sprintf("%q isn't recorded in past versions, so it can't be exported with as_of; allowed fields are %s",
+					field, strings.Join(services.AsOfCountryFields, ", ")),
+			})
+			return nil, false
+		}
+	}
+	return projection.Fields, true
+}
+
+// countryStream calls fn with every exported country, stopping at the first error
+type countryStream func(fn func(*models.Country) error) error
+
+// writeStreamedExport writes the countries of stream as CSV or NDJSON. The export headers and the CSV header row
+// are only written once the first country arrives, or the stream ends, so a stream that fails before any of
+// the export reaches the client gets a JSON error response instead.
+func writeStreamedExport(c *gin.Context, format string, columns []string, projection services.CountryProjection, stream countryStream) {
+	var (
+		write   func(*models.Country) error
+		flush   func() error
+		started bool
+	)
+	start := func() error {
+		started = true
+		c.Header("Content-Type", exportContentTypes[format]+"; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="countries.`+format+`"`)
+
+		switch format {
+		case exportCSV:
+			w := csv.NewWriter(c.Writer)
+			write = func(country *models.Country) error {
+				record := make([]string, len(columns))
+				for i, column := range columns {
+					record[i] = csvValue(exportValue(country, column))
+				}
+				return w.Write(record)
+			}
+			flush = func() error {
+				w.Flush()
+				return w.Error()
+			}
+			return w.Write(columns)
+		case exportNDJSON:
+			encoder := json.NewEncoder(c.Writer)
+			write = func(country *models.Country) error {
+				row, err := projectCountry(country, projection)
+				if err != nil {
+					return err
+				}
+				return encoder.Encode(row)
+			}
+			flush = func() error { return nil }
+		}
+		return nil
+	}
+
+	rows := 0
+	err := stream(func(country *models.Country) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := write(country); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushInterval == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start() // No countries; the export is just the CSV header row
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			// Nothing has reached the client, so the buffered rows are dropped for an error response
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			utils.HandleInternalServerError(c, err, "failed to export countries")
+			return
+		}
+		// The status has been sent, so the truncated body is all the client gets
+		log.Printf("Failed to export countries after %d rows: %v", rows, err)
+	}
+}
+
+// exportXLSX writes every country matching filter to a worksheet with a header row and typed cells
+func (ctrl *CountryController) exportXLSX(c *gin.Context, filter services.CountryFilter, projection services.CountryProjection, columns []string) {
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", xlsxSheet); err != nil {
+		utils.HandleInternalServerError(c, err, "failed to create worksheet")
+		return
+	}
+	sw, err := f.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to create worksheet")
+		return
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to create worksheet")
+		return
+	}
+
+	// Keep the header visible while scrolling; the stream writer needs panes before any row
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		utils.HandleInternalServerError(c, err, "failed to write worksheet")
+		return
+	}
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = excelize.Cell{StyleID: bold, Value: column}
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		utils.HandleInternalServerError(c, err, "failed to write worksheet")
+		return
+	}
+
+	row := 1
+	err = ctrl.countryService.StreamCountries(filter, projection, func(country *models.Country) error {
+		row++
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			values[i] = xlsxValue(exportValue(country, column))
+		}
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		return sw.SetRow(cell, values)
+	})
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to export countries")
+		return
+	}
+	if err := sw.Flush(); err != nil {
+		utils.HandleInternalServerError(c, err, "failed to write worksheet")
+		return
+	}
+
+	c.Header("Content-Type", mimeXLSX)
+	c.Header("Content-Disposition", `attachment; filename="countries.xlsx"`)
+	if err := f.Write(c.Writer); err != nil {
+		log.Printf("Failed to write XLSX export: %v", err)
+	}
+}
+
+// exportValue returns the value of a field of country, by JSON name, with pointers dereferenced.
+// It returns nil for NULL values.
+func exportValue(country *models.Country, field string) interface{} {
+	switch field {
+	case "id":
+		return country.ID
+	case "name":
+		return country.Name
+	case "capital":
+		return derefExport(country.Capital)
+	case "region":
+		return derefExport(country.Region)
+	case "population":
+		return country.Population
+	case "currency_code":
+		return derefExport(country.CurrencyCode)
+	case "exchange_rate":
+		return derefExport(country.ExchangeRate)
+	case "estimated_gdp":
+		return derefExport(country.EstimatedGDP)
+	case "gdp_estimator":
+		return derefExport(country.GDPEstimator)
+	case "gdp_estimator_params":
+		if country.GDPEstimatorParams == nil {
+			return nil
+		}
+		raw, _ := json.Marshal(country.GDPEstimatorParams)
+		return string(raw)
+	case "flag_url":
+		return derefExport(country.FlagURL)
+	case "alt_names":
+		if len(country.AltNames) == 0 {
+			return nil
+		}
+		return strings.Join(country.AltNames, "; ")
+	case "last_refreshed_at":
+		return country.LastRefreshedAt
+	case "stale":
+		return country.Stale
+	case "origin":
+		return country.Origin
+	}
+	return nil
+}
+
+// derefExport returns the value p points to, or nil if p is nil
+func derefExport[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// csvFormulaPrefixes are the first characters that make spreadsheets read a cell as a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvValue formats an exported value as a CSV field; NULL values are empty.
+// Strings that a spreadsheet would run as a formula are prefixed with ' so they stay text.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && strings.ContainsRune(csvFormulaPrefixes, rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// xlsxValue converts an exported value to a cell value; times are written in UTC so Excel shows them unshifted
+func xlsxValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC()
+	}
+	return value
+}
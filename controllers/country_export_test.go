@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"stage-2/models"
+	"stage-2/services"
+)
+
+// sliceStream returns a countryStream of countries that fails with err after them, if err is set
+func sliceStream(countries []models.Country, err error) countryStream {
+	return func(fn func(*models.Country) error) error {
+		for i := range countries {
+			if err := fn(&countries[i]); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+func TestWriteStreamedExport(t *testing.T) {
+	capital := "Abuja"
+	countries := []models.Country{
+		{ID: 1, Name: "Nigeria", Capital: &capital, Population: 206139589},
+		{ID: 2, Name: "Ghana", Population: 31072940},
+	}
+	columns := []string{"name", "capital", "population"}
+	errDB := errors.New("connection reset")
+
+	tests := []struct {
+		name            string
+		format          string
+		stream          countryStream
+		wantStatus      int
+		wantContentType string
+		wantAttachment  bool
+		wantBody        string // Checked exactly unless empty
+	}{
+		{
+			name:            "csv",
+			format:          exportCSV,
+			stream:          sliceStream(countries, nil),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantAttachment:  true,
+			wantBody:        "name,capital,population\nNigeria,Abuja,206139589\nGhana,,31072940\n",
+		},
+		{
+			name:            "csv without countries",
+			format:          exportCSV,
+			stream:          sliceStream(nil, nil),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantAttachment:  true,
+			wantBody:        "name,capital,population\n",
+		},
+		{
+			name:            "csv failing before any country",
+			format:          exportCSV,
+			stream:          sliceStream(nil, errDB),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json; charset=utf-8",
+		},
+		{
+			// The rows so far are still buffered, so the client gets an error rather than part of a file
+			name:            "csv failing before the first flush",
+			format:          exportCSV,
+			stream:          sliceStream(countries, errDB),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json; charset=utf-8",
+		},
+		{
+			name:            "ndjson",
+			format:          exportNDJSON,
+			stream:          sliceStream(countries[1:], nil),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson; charset=utf-8",
+			wantAttachment:  true,
+		},
+		{
+			name:            "ndjson failing before any country",
+			format:          exportNDJSON,
+			stream:          sliceStream(nil, errDB),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json; charset=utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext("/countries", "format="+tt.format)
+			writeStreamedExport(c, tt.format, columns, services.CountryProjection{}, tt.stream)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Header().Get("Content-Disposition"); (got != "") != tt.wantAttachment {
+				t.Errorf("Content-Disposition = %q, want an attachment: %v", got, tt.wantAttachment)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				// The whole body is the error, without any CSV after it
+				var body map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("error body %q isn't JSON: %v", w.Body, err)
+				}
+				if body["error"] != "Internal server error" {
+					t.Errorf("error body = %v", body)
+				}
+				return
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCSVValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"null", nil, ""},
+		{"string", "Abuja", "Abuja"},
+		{"empty string", "", ""},
+		{"formula", "=HYPERLINK(\"http://example.com\")", "'=HYPERLINK(\"http://example.com\")"},
+		{"plus", "+2348012345678", "'+2348012345678"},
+		{"minus", "-1+1", "'-1+1"},
+		{"at", "@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"carriage return", "\r=1", "'\r=1"},
+		{"sign inside", "Guinea-Bissau", "Guinea-Bissau"},
+		{"negative number", -1.5, "-1.5"},
+		{"integer", uint64(206139589), "206139589"},
+		{"large float", 4.5e11, "450000000000"},
+		{"boolean", true, "true"},
+		{"time", time.Date(2025, 10, 22, 19, 0, 0, 0, time.FixedZone("WAT", 3600)), "2025-10-22T18:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csvValue(tt.value); got != tt.want {
+				t.Errorf("csvValue(%#v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportColumns(t *testing.T) {
+	asOf := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		asOf    *time.Time
+		fields  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "every field", want: services.SelectableCountryFields},
+		{name: "selected fields", fields: []string{"origin", "name"}, want: []string{"origin", "name"}},
+		{name: "every recorded field as of a time", asOf: &asOf, want: services.AsOfCountryFields},
+		{name: "recorded fields as of a time", asOf: &asOf, fields: []string{"name", "estimated_gdp"}, want: []string{"name", "estimated_gdp"}},
+		{name: "stale as of a time", asOf: &asOf, fields: []string{"name", "stale"}, wantErr: true},
+		{name: "origin as of a time", asOf: &asOf, fields: []string{"origin"}, wantErr: true},
+		{name: "alt_names as of a time", asOf: &asOf, fields: []string{"alt_names"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext("/countries", "format=csv")
+			got, ok := exportColumns(c, services.CountryFilter{AsOf: tt.asOf}, services.CountryProjection{Fields: tt.fields})
+			if tt.wantErr {
+				if ok {
+					t.Fatalf("exportColumns() = %v, want an error", got)
+				}
+				if errs := validationErrors(t, w); errs["fields"] == "" {
+					t.Errorf("errors = %v, want one for fields", errs)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("exportColumns() failed: %s", w.Body)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("exportColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, field := range []string{"stale", "origin", "alt_names"} {
+		if slices.Contains(services.AsOfCountryFields, field) {
+			t.Errorf("AsOfCountryFields has %s, which snapshots don't record", field)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"stage-2/models"
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseCountryFilter reads the filter, sort and as_of query parameters shared by the country listing endpoints.
+// It writes a 400 response listing every invalid parameter and returns false if any is invalid.
+func parseCountryFilter(c *gin.Context) (services.CountryFilter, bool) {
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return services.CountryFilter{}, false
+	}
+	filter := services.CountryFilter{
+		Regions:    splitList(c.Query("region")),
+		Currencies: splitList(c.Query("currency")),
+		Sort:       c.Query("sort"), // e.g., region,-estimated_gdp,name
+		Nulls:      c.Query("nulls"),
+		AsOf:       asOf,
+	}
+
+	errs := make(map[string]string)
+	for key, msg := range services.ValidateSort(filter.Sort, filter.Nulls) {
+		errs[key] = msg
+	}
+	filter.PopulationMin = parseUintQuery(c, "population_min", errs)
+	filter.PopulationMax = parseUintQuery(c, "population_max", errs)
+	filter.GDPMin = parseFloatQuery(c, "gdp_min", errs)
+	filter.GDPMax = parseFloatQuery(c, "gdp_max", errs)
+	filter.ExchangeRateMin = parseFloatQuery(c, "exchange_rate_min", errs)
+	filter.ExchangeRateMax = parseFloatQuery(c, "exchange_rate_max", errs)
+	filter.HasCurrency = parseBoolQuery(c, "has_currency", errs)
+	filter.HasRate = parseBoolQuery(c, "has_rate", errs)
+
+	if filter.PopulationMin != nil && filter.PopulationMax != nil && *filter.PopulationMin > *filter.PopulationMax {
+		errs["population_min"] = "must not be greater than population_max"
+	}
+	if filter.GDPMin != nil && filter.GDPMax != nil && *filter.GDPMin > *filter.GDPMax {
+		errs["gdp_min"] = "must not be greater than gdp_max"
+	}
+	if filter.ExchangeRateMin != nil && filter.ExchangeRateMax != nil && *filter.ExchangeRateMin > *filter.ExchangeRateMax {
+		errs["exchange_rate_min"] = "must not be greater than exchange_rate_max"
+	}
+
+	if len(errs) > 0 {
+		utils.HandleBadRequestError(c, errs)
+		return filter, false
+	}
+	return filter, true
+}
+
+// splitList splits a comma-separated query value, dropping empty entries
+func splitList(raw string) []string {
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseUintQuery reads an optional non-negative integer query parameter, recording an error in errs if it is invalid
+func parseUintQuery(c *gin.Context, key string, errs map[string]string) *uint64 {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		errs[key] = "must be a non-negative integer"
+		return nil
+	}
+	return &v
+}
+
+// parseFloatQuery reads an optional non-negative number query parameter, recording an error in errs if it is invalid
+func parseFloatQuery(c *gin.Context, key string, errs map[string]string) *float64 {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		errs[key] = "must be a non-negative number"
+		return nil
+	}
+	return &v
+}
+
+// parseBoolQuery reads an optional true/false query parameter, recording an error in errs if it is invalid
+func parseBoolQuery(c *gin.Context, key string, errs map[string]string) *bool {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		errs[key] = "must be true or false"
+		return nil
+	}
+	return &v
+}
+
+// parseCountryProjection reads the fields and include query parameters of the country endpoints.
+// It writes a 400 response and returns false if either is invalid.
+func parseCountryProjection(c *gin.Context) (services.CountryProjection, bool) {
+	projection := services.CountryProjection{
+		Fields:  splitList(c.Query("fields")),
+		Include: splitList(c.Query("include")),
+	}
+	if errs := services.ValidateProjection(projection); errs != nil {
+		utils.HandleBadRequestError(c, errs)
+		return projection, false
+	}
+	return projection, true
+}
+
+// projectCountry returns the JSON form of country limited to the selected fields and included relations,
+// or country itself if no fields are selected
+func projectCountry(country *models.Country, projection services.CountryProjection) (interface{}, error) {
+	if len(projection.Fields) == 0 {
+		return country, nil
+	}
+	raw, err := json.Marshal(country)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]interface{}
+	if err := json.Unmarshal(raw, &full); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]interface{}, len(projection.Fields)+len(projection.Include))
+	for _, key := range append(append([]string{}, projection.Fields...), projection.Include...) {
+		if value, ok := full[key]; ok {
+			projected[key] = value
+		}
+	}
+	return projected, nil
+}
+
+// projectCountries applies projectCountry to every country
+func projectCountries(countries []models.Country, projection services.CountryProjection) (interface{}, error) {
+	if len(projection.Fields) == 0 {
+		return countries, nil
+	}
+	projected := make([]interface{}, len(countries))
+	for i := range countries {
+		var err error
+		if projected[i], err = projectCountry(&countries[i], projection); err != nil {
+			return nil, err
+		}
+	}
+	return projected, nil
+}
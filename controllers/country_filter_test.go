@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"stage-2/services"
+)
+
+func TestParseCountryFilterRanges(t *testing.T) {
+	uintPtr := func(v uint64) *uint64 { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+
+	tests := []struct {
+		name     string
+		query    string
+		want     services.CountryFilter
+		wantErrs map[string]string // nil if the query is valid
+	}{
+		{name: "no filters", query: "", want: services.CountryFilter{}},
+		{
+			name:  "lists",
+			query: "region=Africa,+Europe,&currency=NGN",
+			want:  services.CountryFilter{Regions: []string{"Africa", "Europe"}, Currencies: []string{"NGN"}},
+		},
+		{
+			name:  "every bound",
+			query: "population_min=1000&population_max=5000&gdp_min=0&gdp_max=1.5e9&exchange_rate_min=0.5&exchange_rate_max=100",
+			want: services.CountryFilter{
+				PopulationMin: uintPtr(1000), PopulationMax: uintPtr(5000),
+				GDPMin: floatPtr(0), GDPMax: floatPtr(1.5e9),
+				ExchangeRateMin: floatPtr(0.5), ExchangeRateMax: floatPtr(100),
+			},
+		},
+		{
+			name:  "equal bounds",
+			query: "population_min=7&population_max=7&gdp_min=2.5&gdp_max=2.5&exchange_rate_min=1&exchange_rate_max=1",
+			want: services.CountryFilter{
+				PopulationMin: uintPtr(7), PopulationMax: uintPtr(7),
+				GDPMin: floatPtr(2.5), GDPMax: floatPtr(2.5),
+				ExchangeRateMin: floatPtr(1), ExchangeRateMax: floatPtr(1),
+			},
+		},
+		{name: "one-sided bound", query: "gdp_max=10", want: services.CountryFilter{GDPMax: floatPtr(10)}},
+		{
+			name:  "flags",
+			query: "has_currency=true&has_rate=0",
+			want:  services.CountryFilter{HasCurrency: boolPtr(true), HasRate: boolPtr(false)},
+		},
+		{
+			name:     "population min above max",
+			query:    "population_min=5000&population_max=1000",
+			wantErrs: map[string]string{"population_min": "must not be greater than population_max"},
+		},
+		{
+			name:     "gdp min above max",
+			query:    "gdp_min=10&gdp_max=9.99",
+			wantErrs: map[string]string{"gdp_min": "must not be greater than gdp_max"},
+		},
+		{
+			name:     "exchange rate min above max",
+			query:    "exchange_rate_min=2&exchange_rate_max=1",
+			wantErrs: map[string]string{"exchange_rate_min": "must not be greater than exchange_rate_max"},
+		},
+		{
+			name:     "negative population",
+			query:    "population_min=-1",
+			wantErrs: map[string]string{"population_min": "must be a non-negative integer"},
+		},
+		{
+			name:     "fractional population",
+			query:    "population_max=1.5",
+			wantErrs: map[string]string{"population_max": "must be a non-negative integer"},
+		},
+		{name: "negative gdp", query: "gdp_min=-0.01", wantErrs: map[string]string{"gdp_min": "must be a non-negative number"}},
+		{name: "NaN gdp", query: "gdp_max=NaN", wantErrs: map[string]string{"gdp_max": "must be a non-negative number"}},
+		{name: "infinite rate", query: "exchange_rate_max=Inf", wantErrs: map[string]string{"exchange_rate_max": "must be a non-negative number"}},
+		{name: "rate not a number", query: "exchange_rate_min=high", wantErrs: map[string]string{"exchange_rate_min": "must be a non-negative number"}},
+		{name: "flag not a boolean", query: "has_rate=maybe", wantErrs: map[string]string{"has_rate": "must be true or false"}},
+		{
+			// An invalid bound isn't compared with the other one
+			name:     "invalid bound in a range",
+			query:    "gdp_min=abc&gdp_max=5",
+			wantErrs: map[string]string{"gdp_min": "must be a non-negative number"},
+		},
+		{
+			name:  "several errors",
+			query: "population_min=9&population_max=1&gdp_min=-5&exchange_rate_min=3&exchange_rate_max=2&has_currency=yes!",
+			wantErrs: map[string]string{
+				"population_min":    "must not be greater than population_max",
+				"gdp_min":           "must be a non-negative number",
+				"exchange_rate_min": "must not be greater than exchange_rate_max",
+				"has_currency":      "must be true or false",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext("/countries", tt.query)
+			got, ok := parseCountryFilter(c)
+			if tt.wantErrs != nil {
+				if ok {
+					t.Fatalf("parseCountryFilter() = %+v, want errors %v", got, tt.wantErrs)
+				}
+				if errs := validationErrors(t, w); !reflect.DeepEqual(errs, tt.wantErrs) {
+					t.Errorf("errors = %v, want %v", errs, tt.wantErrs)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("parseCountryFilter() failed: %s", w.Body)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCountryFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
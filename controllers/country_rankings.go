@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Leaderboard size limits
+const (
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 500
+)
+
+// GetLeaderboard handles the GET /rankings/:metric endpoint
+func (ctrl *CountryController) GetLeaderboard(c *gin.Context) {
+	metric := c.Param("metric")
+	if !slices.Contains(services.RankedMetrics, metric) {
+		utils.HandleBadRequestError(c, map[string]string{"metric": "must be one of " + strings.Join(services.RankedMetrics, ", ")})
+		return
+	}
+
+	limit := defaultLeaderboardLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxLeaderboardLimit {
+			utils.HandleBadRequestError(c, map[string]string{"limit": "must be an integer between 1 and " + strconv.Itoa(maxLeaderboardLimit)})
+			return
+		}
+		limit = parsed
+	}
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	region := strings.TrimSpace(c.Query("region"))
+	entries, err := ctrl.countryService.GetLeaderboard(metric, region, asOf, limit)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get leaderboard")
+		return
+	}
+
+	response := gin.H{"metric": metric, "rankings": entries}
+	if region != "" {
+		response["region"] = region
+	}
+	if asOf != nil {
+		response["as_of"] = asOf
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search result limits; typeahead requests get fewer results, since they run on every keystroke
+const (
+	defaultSearchLimit    = 20
+	maxSearchLimit        = 100
+	defaultTypeaheadLimit = 5
+	maxTypeaheadLimit     = 10
+)
+
+// SearchCountries handles the GET /countries/search endpoint
+func (ctrl *CountryController) SearchCountries(c *gin.Context) {
+	errs := make(map[string]string)
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		errs["q"] = "is required"
+	}
+	typeahead := parseBoolQuery(c, "typeahead", errs)
+
+	search := services.CountrySearch{Query: query, Limit: defaultSearchLimit}
+	maxLimit := maxSearchLimit
+	if typeahead != nil && *typeahead {
+		search.Typeahead = true
+		search.Limit = defaultTypeaheadLimit
+		maxLimit = maxTypeaheadLimit
+	}
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxLimit {
+			errs["limit"] = "must be an integer between 1 and " + strconv.Itoa(maxLimit)
+		}
+		search.Limit = parsed
+	}
+	if len(errs) > 0 {
+		utils.HandleBadRequestError(c, errs)
+		return
+	}
+
+	results, err := ctrl.countryService.SearchCountries(search)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to search countries")
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCountryStats handles the GET /countries/stats endpoint
+func (ctrl *CountryController) GetCountryStats(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", services.GroupByRegion)
+	if groupBy != services.GroupByRegion && groupBy != services.GroupByCurrency {
+		utils.HandleBadRequestError(c, map[string]string{"group_by": "must be region or currency"})
+		return
+	}
+	filter, ok := parseCountryFilter(c)
+	if !ok {
+		return
+	}
+
+	stats, err := ctrl.countryService.GetCountryStats(filter, groupBy)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get country stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"group_by": groupBy, "groups": stats})
+}
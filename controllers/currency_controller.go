@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRateWindow is how far back GET /currencies/:code/rates looks when from is not given
+const defaultRateWindow = 30 * 24 * time.Hour
+
+// CurrencyController handles HTTP requests related to currencies
+type CurrencyController struct {
+	currencyService     *services.CurrencyService
+	exchangeRateService *services.ExchangeRateService
+}
+
+// NewCurrencyController creates a new CurrencyController
+func NewCurrencyController(cs *services.CurrencyService, ers *services.ExchangeRateService) *CurrencyController {
+	return &CurrencyController{currencyService: cs, exchangeRateService: ers}
+}
+
+// GetCurrencies handles the GET /currencies endpoint
+func (ctrl *CurrencyController) GetCurrencies(c *gin.Context) {
+	currencies, err := ctrl.currencyService.GetCurrencies()
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get currencies")
+		return
+	}
+
+	c.JSON(http.StatusOK, currencies)
+}
+
+// GetCurrencyByCode handles the GET /currencies/:code endpoint
+func (ctrl *CurrencyController) GetCurrencyByCode(c *gin.Context) {
+	code := c.Param("code")
+
+	currency, err := ctrl.currencyService.GetCurrencyByCode(code)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get currency by code")
+		return
+	}
+	if currency == nil {
+		utils.HandleNotFoundError(c, "Currency")
+		return
+	}
+
+	c.JSON(http.StatusOK, currency)
+}
+
+// GetRateSeries handles the GET /currencies/:code/rates endpoint
+func (ctrl *CurrencyController) GetRateSeries(c *gin.Context) {
+	code := c.Param("code")
+
+	to, ok := parseTimeQuery(c, "to", true)
+	if !ok {
+		return
+	}
+	if to == nil {
+		now := time.Now().UTC()
+		to = &now
+	}
+	from, ok := parseTimeQuery(c, "from", false)
+	if !ok {
+		return
+	}
+	if from == nil {
+		start := to.Add(-defaultRateWindow)
+		from = &start
+	}
+	if from.After(*to) {
+		utils.HandleBadRequestError(c, map[string]string{"from": "must not be after to"})
+		return
+	}
+
+	interval := c.Query("interval")
+	if _, ok := services.RateIntervals[interval]; interval != "" && !ok {
+		allowed := make([]string, 0, len(services.RateIntervals))
+		for name := range services.RateIntervals {
+			allowed = append(allowed, name)
+		}
+		sort.Strings(allowed)
+		utils.HandleBadRequestError(c, map[string]string{"interval": "must be one of " + strings.Join(allowed, ", ")})
+		return
+	}
+
+	series, err := ctrl.exchangeRateService.GetRateSeries(code, *from, *to, interval)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get rate history")
+		return
+	}
+	if series == nil {
+		utils.HandleNotFoundError(c, "Currency")
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// Convert handles the GET /convert endpoint
+func (ctrl *CurrencyController) Convert(c *gin.Context) {
+	errs := make(map[string]string)
+	from, to := c.Query("from"), c.Query("to")
+	for key, code := range map[string]string{"from": from, "to": to} {
+		if code == "" {
+			errs[key] = "is required"
+		} else if !services.ValidCurrencyCode(code) {
+			errs[key] = "must be a 3-letter currency code"
+		}
+	}
+	amount := 1.0
+	if parsed := parseFloatQuery(c, "amount", errs); parsed != nil {
+		amount = *parsed
+	}
+	if len(errs) > 0 {
+		utils.HandleBadRequestError(c, errs)
+		return
+	}
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	conversion, err := ctrl.exchangeRateService.Convert(from, to, amount, asOf)
+	if err != nil {
+		var unknown *services.UnknownCurrencyError
+		var unavailable *services.RateUnavailableError
+		var stale *services.StaleRateError
+		switch {
+		case errors.As(err, &unknown):
+			utils.HandleNotFoundError(c, "Currency "+unknown.Code)
+		case errors.As(err, &unavailable):
+			utils.HandleNotFoundError(c, "Exchange rate for "+unavailable.Code+" as of "+unavailable.AsOf.Format(time.RFC3339))
+		case errors.As(err, &stale):
+			utils.HandleUnprocessableEntityError(c, "Exchange rate is stale", stale.Error())
+		default:
+			utils.HandleInternalServerError(c, err, "failed to convert currency")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, conversion)
+}
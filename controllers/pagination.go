@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// parsePagination reads the page, per_page and cursor query parameters.
+// Without any of them every row is returned, as before pagination existed.
+// It writes a 400 response and returns false if a value is invalid.
+func parsePagination(c *gin.Context) (services.Pagination, bool) {
+	var page services.Pagination
+	errs := make(map[string]string)
+
+	rawPage, rawPerPage := c.Query("page"), c.Query("per_page")
+	page.Cursor = c.Query("cursor")
+	if rawPage == "" && rawPerPage == "" && page.Cursor == "" {
+		return page, true
+	}
+
+	page.Page, page.PerPage = 1, defaultPerPage
+	if rawPage != "" {
+		parsed, err := strconv.Atoi(rawPage)
+		if err != nil || parsed < 1 {
+			errs["page"] = "must be a positive integer"
+		}
+		page.Page = parsed
+	}
+	if rawPerPage != "" {
+		parsed, err := strconv.Atoi(rawPerPage)
+		if err != nil || parsed < 1 || parsed > maxPerPage {
+			errs["per_page"] = "must be an integer between 1 and " + strconv.Itoa(maxPerPage)
+		}
+		page.PerPage = parsed
+	}
+	if page.Cursor != "" && rawPage != "" {
+		errs["page"] = "can't be combined with cursor"
+	}
+
+	if len(errs) > 0 {
+		utils.HandleBadRequestError(c, errs)
+		return page, false
+	}
+	return page, true
+}
+
+// writePage writes a page of results with X-Total-Count and RFC 8288 Link headers.
+// The body is the bare list, or a {"data", "meta"} envelope if the envelope query parameter is true.
+func writePage(c *gin.Context, data interface{}, page services.Pagination, total int64, nextCursor string) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+
+	meta := gin.H{"total": total}
+	if nextCursor != "" {
+		meta["next_cursor"] = nextCursor
+	}
+	var links []string
+	switch {
+	case page.PerPage > 0 && page.Cursor != "":
+		// Cursors only move forward
+		meta["per_page"] = page.PerPage
+		if nextCursor != "" {
+			links = append(links, pageLink(c, "next", map[string]string{"cursor": nextCursor}))
+		}
+	case page.PerPage > 0:
+		lastPage := max(int((total+int64(page.PerPage)-1)/int64(page.PerPage)), 1)
+		meta["page"] = page.Page
+		meta["per_page"] = page.PerPage
+		meta["total_pages"] = lastPage
+
+		links = append(links, pageLink(c, "first", map[string]string{"page": "1"}))
+		if page.Page > 1 {
+			links = append(links, pageLink(c, "prev", map[string]string{"page": strconv.Itoa(min(page.Page-1, lastPage))}))
+		}
+		if nextCursor != "" {
+			links = append(links, pageLink(c, "next", map[string]string{"page": strconv.Itoa(page.Page + 1)}))
+		}
+		links = append(links, pageLink(c, "last", map[string]string{"page": strconv.Itoa(lastPage)}))
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+
+	if envelope, _ := strconv.ParseBool(c.Query("envelope")); envelope {
+		c.JSON(http.StatusOK, gin.H{"data": data, "meta": meta})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// pageLink builds a Link header entry for the current URL with some query parameters replaced
+func pageLink(c *gin.Context, rel string, params map[string]string) string {
+	query := c.Request.URL.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	return fmt.Sprintf("<%s?%s>; rel=\"%s\"", c.Request.URL.Path, query.Encode(), rel)
+}
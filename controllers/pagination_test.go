@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"stage-2/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestContext returns a context for a GET of path with rawQuery, and the recorder it writes to
+func newTestContext(path, rawQuery string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, path+"?"+rawQuery, nil)
+	return c, w
+}
+
+// validationErrors returns the details of a 400 response written to w
+func validationErrors(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body struct {
+		Error   string            `json:"error"`
+		Details map[string]string `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid error body %s: %v", w.Body, err)
+	}
+	return body.Details
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		want     services.Pagination
+		wantErrs map[string]string // nil if the query is valid
+	}{
+		{name: "no parameters", query: "", want: services.Pagination{}},
+		{name: "other parameters", query: "region=Africa&sort=-population", want: services.Pagination{}},
+		{name: "page only", query: "page=3", want: services.Pagination{Page: 3, PerPage: defaultPerPage}},
+		{name: "per_page only", query: "per_page=10", want: services.Pagination{Page: 1, PerPage: 10}},
+		{name: "both", query: "page=2&per_page=200", want: services.Pagination{Page: 2, PerPage: 200}},
+		{name: "cursor", query: "cursor=abc", want: services.Pagination{Page: 1, PerPage: defaultPerPage, Cursor: "abc"}},
+		{name: "cursor with per_page", query: "cursor=abc&per_page=5", want: services.Pagination{Page: 1, PerPage: 5, Cursor: "abc"}},
+		{name: "page zero", query: "page=0", wantErrs: map[string]string{"page": "must be a positive integer"}},
+		{name: "page not a number", query: "page=two", wantErrs: map[string]string{"page": "must be a positive integer"}},
+		{name: "per_page zero", query: "per_page=0", wantErrs: map[string]string{"per_page": "must be an integer between 1 and 200"}},
+		{name: "per_page too large", query: "per_page=201", wantErrs: map[string]string{"per_page": "must be an integer between 1 and 200"}},
+		{name: "page with cursor", query: "page=2&cursor=abc", wantErrs: map[string]string{"page": "can't be combined with cursor"}},
+		{
+			name:     "several errors",
+			query:    "page=-1&per_page=x",
+			wantErrs: map[string]string{"page": "must be a positive integer", "per_page": "must be an integer between 1 and 200"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext("/countries", tt.query)
+			got, ok := parsePagination(c)
+			if tt.wantErrs != nil {
+				if ok {
+					t.Fatalf("parsePagination() = %+v, want errors %v", got, tt.wantErrs)
+				}
+				if errs := validationErrors(t, w); !reflect.DeepEqual(errs, tt.wantErrs) {
+					t.Errorf("errors = %v, want %v", errs, tt.wantErrs)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("parsePagination() failed: %s", w.Body)
+			}
+			if got != tt.want {
+				t.Errorf("parsePagination() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePageLinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		page       services.Pagination
+		total      int64
+		nextCursor string
+		wantLink   string
+	}{
+		{name: "unpaginated", query: "", total: 3},
+		{
+			name:     "first page",
+			query:    "page=1&per_page=10",
+			page:     services.Pagination{Page: 1, PerPage: 10},
+			total:    25,
+			wantLink: `</countries?page=1&per_page=10>; rel="first", </countries?page=3&per_page=10>; rel="last"`,
+		},
+		{
+			name:       "middle page",
+			query:      "page=2&per_page=10&region=Africa",
+			page:       services.Pagination{Page: 2, PerPage: 10},
+			total:      25,
+			nextCursor: "next",
+			wantLink: `</countries?page=1&per_page=10&region=Africa>; rel="first", ` +
+				`</countries?page=1&per_page=10&region=Africa>; rel="prev", ` +
+				`</countries?page=3&per_page=10&region=Africa>; rel="next", ` +
+				`</countries?page=3&per_page=10&region=Africa>; rel="last"`,
+		},
+		{
+			name:     "past the last page",
+			query:    "page=9&per_page=10",
+			page:     services.Pagination{Page: 9, PerPage: 10},
+			total:    25,
+			wantLink: `</countries?page=1&per_page=10>; rel="first", </countries?page=3&per_page=10>; rel="prev", </countries?page=3&per_page=10>; rel="last"`,
+		},
+		{
+			name:     "no rows",
+			query:    "per_page=10",
+			page:     services.Pagination{Page: 1, PerPage: 10},
+			wantLink: `</countries?page=1&per_page=10>; rel="first", </countries?page=1&per_page=10>; rel="last"`,
+		},
+		{
+			name:       "cursor",
+			query:      "cursor=abc&per_page=10",
+			page:       services.Pagination{Page: 1, PerPage: 10, Cursor: "abc"},
+			total:      25,
+			nextCursor: "def",
+			wantLink:   `</countries?cursor=def&per_page=10>; rel="next"`,
+		},
+		{
+			name:     "last cursor page",
+			query:    "cursor=abc&per_page=10",
+			page:     services.Pagination{Page: 1, PerPage: 10, Cursor: "abc"},
+			total:    25,
+			wantLink: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext("/countries", tt.query)
+			writePage(c, []string{}, tt.page, tt.total, tt.nextCursor)
+			if got := w.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %s\nwant %s", got, tt.wantLink)
+			}
+		})
+	}
+}
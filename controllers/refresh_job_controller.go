@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"stage-2/services"
+	"stage-2/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshJobController handles HTTP requests related to refresh jobs
+type RefreshJobController struct {
+	refreshJobService *services.RefreshJobService
+}
+
+// NewRefreshJobController creates a new RefreshJobController
+func NewRefreshJobController(js *services.RefreshJobService) *RefreshJobController {
+	return &RefreshJobController{refreshJobService: js}
+}
+
+// GetRefreshJob handles the GET /refresh-jobs/:id endpoint
+func (ctrl *RefreshJobController) GetRefreshJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.HandleBadRequestError(c, map[string]string{"id": "must be a positive integer"})
+		return
+	}
+
+	job, err := ctrl.refreshJobService.GetJob(uint(id))
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get refresh job")
+		return
+	}
+	if job == nil {
+		utils.HandleNotFoundError(c, "Refresh job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
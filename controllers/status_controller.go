@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
 	"stage-2/services"
 	"stage-2/utils"
@@ -11,12 +12,13 @@ import (
 
 // StatusController handles HTTP requests related to the application status
 type StatusController struct {
-	statusService *services.StatusService
+	statusService    *services.StatusService
+	schedulerService *services.SchedulerService
 }
 
 // NewStatusController creates a new StatusController
-func NewStatusController(ss *services.StatusService) *StatusController {
-	return &StatusController{statusService: ss}
+func NewStatusController(ss *services.StatusService, sched *services.SchedulerService) *StatusController {
+	return &StatusController{statusService: ss, schedulerService: sched}
 }
 
 // GetStatus handles the GET /status endpoint
@@ -27,8 +29,96 @@ func (ctrl *StatusController) GetStatus(c *gin.Context) {
 		return
 	}
 
+	latestRun, err := ctrl.statusService.GetLatestRefreshRun()
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get latest refresh run")
+		return
+	}
+	staleCountries, err := ctrl.statusService.CountStaleCountries()
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to count stale countries")
+		return
+	}
+
+	var lastReconciliation gin.H
+	if latestRun != nil {
+		lastReconciliation = gin.H{
+			"refresh_run_id": latestRun.ID,
+			"policy":         latestRun.ReconcilePolicy,
+			"stale_flagged":  latestRun.StaleFlagged,
+			"soft_deleted":   latestRun.SoftDeleted,
+			"hard_deleted":   latestRun.HardDeleted,
+		}
+	}
+
+	var nextScheduledRun *string
+	if next := ctrl.schedulerService.NextRun(); next != nil {
+		formatted := next.Format("2006-01-02T15:04:05Z")
+		nextScheduledRun = &formatted
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_countries":       status.TotalCountries,
+		"last_refreshed_at":     status.LastRefreshedAt.Format("2006-01-02T15:04:05Z"),
+		"last_refreshed_by":     status.LastRefreshedBy,
+		"next_scheduled_run_at": nextScheduledRun,
+		"stale_countries":       staleCountries,
+		"last_reconciliation":   lastReconciliation,
+	})
+}
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// GetRefreshHistory handles the GET /status/history endpoint
+func (ctrl *StatusController) GetRefreshHistory(c *gin.Context) {
+	limit := defaultHistoryLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			utils.HandleBadRequestError(c, map[string]string{"limit": "must be an integer between 1 and " + strconv.Itoa(maxHistoryLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := ctrl.statusService.GetRefreshHistory(limit)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get refresh history")
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetRefreshChanges handles the GET /status/history/:id/changes endpoint
+func (ctrl *StatusController) GetRefreshChanges(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.HandleBadRequestError(c, map[string]string{"id": "must be a positive integer"})
+		return
+	}
+
+	run, err := ctrl.statusService.GetRefreshRun(uint(id))
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get refresh run")
+		return
+	}
+	if run == nil {
+		utils.HandleNotFoundError(c, "Refresh run")
+		return
+	}
+
+	changes, err := ctrl.statusService.GetRefreshChanges(run.ID)
+	if err != nil {
+		utils.HandleInternalServerError(c, err, "failed to get refresh changes")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"total_countries":   status.TotalCountries,
-		"last_refreshed_at": status.LastRefreshedAt.Format("2006-01-02T15:04:05Z"),
+		"run":     run,
+		"changes": changes,
 	})
 }
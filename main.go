@@ -35,33 +35,137 @@ func main() {
 
 	// Auto-migrate models
 	log.Println("Attempting to auto-migrate database models...")
-	err = db.AutoMigrate(&models.Country{}, &models.Status{})
+	if err := config.ApplySchemaMigrations(db); err != nil {
+		log.Fatalf("Failed to apply database schema migrations: %v", err)
+	}
+	err = db.AutoMigrate(&models.Country{}, &models.CountryOverride{}, &models.Currency{}, &models.Status{}, &models.RefreshJob{}, &models.RefreshRun{}, &models.CountryChange{}, &models.CountrySnapshot{}, &models.ExchangeRate{}, &models.AuditEvent{})
 	if err != nil {
 		log.Fatalf("Failed to auto-migrate database: %v", err)
 	}
+	if err := config.ApplySchemaExtras(db); err != nil {
+		log.Fatalf("Failed to apply database schema extras: %v", err)
+	}
 	log.Println("Database auto-migration successful.")
 
+	// Load scheduler configuration
+	schedulerConfig, err := config.LoadSchedulerConfig()
+	if err != nil {
+		log.Fatalf("Invalid scheduler configuration: %v", err)
+	}
+
+	// Load data provider configuration
+	providerConfig, err := config.LoadProviderConfig()
+	if err != nil {
+		log.Fatalf("Invalid data provider configuration: %v", err)
+	}
+
+	// Initialize data providers
+	httpConfig := config.LoadHTTPConfig()
+	httpClient, err := utils.NewFixtureHTTPClient(utils.HTTPMode(httpConfig.Mode), httpConfig.FixturesDir)
+	if err != nil {
+		log.Fatalf("Invalid HTTP client configuration: %v", err)
+	}
+	if httpConfig.Mode != string(utils.HTTPModeLive) {
+		log.Printf("HTTP client running in %s mode with fixtures in %s.", httpConfig.Mode, httpConfig.FixturesDir)
+	}
+	countryProvider, err := services.NewCountryProvider(providerConfig, httpClient)
+	if err != nil {
+		log.Fatalf("Failed to create country provider: %v", err)
+	}
+	rateProvider, err := services.NewRateProvider(providerConfig, httpClient)
+	if err != nil {
+		log.Fatalf("Failed to create rate provider: %v", err)
+	}
+	log.Printf("Using country provider %q (%s) and rate provider %q (%s).",
+		providerConfig.CountryProvider, countryProvider.Source(), providerConfig.RateProvider, rateProvider.Source())
+
+	// Initialize GDP estimator
+	gdpConfig, err := config.LoadGDPConfig()
+	if err != nil {
+		log.Fatalf("Invalid GDP estimator configuration: %v", err)
+	}
+	gdpEstimator, err := services.NewGDPEstimator(gdpConfig)
+	if err != nil {
+		log.Fatalf("Failed to create GDP estimator: %v", err)
+	}
+
+	// Load refresh configuration
+	refreshConfig, err := config.LoadRefreshConfig()
+	if err != nil {
+		log.Fatalf("Invalid refresh configuration: %v", err)
+	}
+
+	// Load exchange rate configuration
+	rateConfig, err := config.LoadRateConfig()
+	if err != nil {
+		log.Fatalf("Invalid exchange rate configuration: %v", err)
+	}
+
 	// Initialize services
-	countryService := services.NewCountryService(db)
+	countryService := services.NewCountryService(db, countryProvider, rateProvider, gdpEstimator, refreshConfig, schedulerConfig.InstanceID)
 	statusService := services.NewStatusService(db)
+	exchangeRateService := services.NewExchangeRateService(db, rateConfig)
+	currencyService := services.NewCurrencyService(db)
+	auditService := services.NewAuditService(db)
+	if err := countryService.BackfillSnapshots(); err != nil {
+		log.Fatalf("Failed to backfill country snapshots: %v", err)
+	}
+	refreshJobService := services.NewRefreshJobService(db, countryService, schedulerConfig.InstanceID)
+
+	// Start the background refresh worker
+	log.Println("Starting refresh job worker...")
+	if err := refreshJobService.Start(); err != nil {
+		log.Fatalf("Failed to start refresh job worker: %v", err)
+	}
+
+	schedulerService, err := services.NewSchedulerService(db, refreshJobService, schedulerConfig)
+	if err != nil {
+		log.Fatalf("Failed to create refresh scheduler: %v", err)
+	}
+	schedulerService.Start()
 
 	// Initialize controllers
-	countryController := controllers.NewCountryController(countryService, statusService)
-	statusController := controllers.NewStatusController(statusService)
+	countryController := controllers.NewCountryController(countryService, statusService, refreshJobService)
+	statusController := controllers.NewStatusController(statusService, schedulerService)
+	refreshJobController := controllers.NewRefreshJobController(refreshJobService)
+	currencyController := controllers.NewCurrencyController(currencyService, exchangeRateService)
+	auditController := controllers.NewAuditController(auditService)
 
 	// Set up Gin router
 	router := gin.Default()
 
+	// Middleware to tag every request with an ID, recorded in the audit log
+	router.Use(utils.RequestIDMiddleware())
+
 	// Middleware to handle external API errors
 	router.Use(utils.ExternalAPIErrorMiddleware())
 
 	// Routes
 	router.POST("/countries/refresh", countryController.RefreshCountries)
 	router.GET("/countries", countryController.GetCountries)
+	router.POST("/countries", countryController.CreateCountry)
+	router.GET("/countries/search", countryController.SearchCountries)
+	router.GET("/countries/stats", countryController.GetCountryStats)
+	router.GET("/countries/compare", countryController.CompareCountries)
 	router.GET("/countries/:name", countryController.GetCountryByName)
+	router.PUT("/countries/:name", countryController.UpdateCountry)
+	router.PATCH("/countries/:name", countryController.PatchCountry)
 	router.DELETE("/countries/:name", countryController.DeleteCountry)
+	router.GET("/countries/trash", countryController.GetTrash)
+	router.POST("/countries/:name/restore", countryController.RestoreCountry)
+	router.DELETE("/countries/trash/:name", countryController.PurgeCountry)
+	router.PUT("/countries/:name/overrides", countryController.SetOverrides)
 	router.GET("/status", statusController.GetStatus)
+	router.GET("/status/history", statusController.GetRefreshHistory)
+	router.GET("/status/history/:id/changes", statusController.GetRefreshChanges)
 	router.GET("/countries/image", countryController.ServeSummaryImage)
+	router.GET("/refresh-jobs/:id", refreshJobController.GetRefreshJob)
+	router.GET("/currencies", currencyController.GetCurrencies)
+	router.GET("/currencies/:code", currencyController.GetCurrencyByCode)
+	router.GET("/currencies/:code/rates", currencyController.GetRateSeries)
+	router.GET("/convert", currencyController.Convert)
+	router.GET("/rankings/:metric", countryController.GetLeaderboard)
+	router.GET("/audit", auditController.GetAuditEvents)
 
 	// Health check route
 	router.GET("/health", func(c *gin.Context) {
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCountryCreate       = "country.create"
+	AuditCountryUpdate       = "country.update"
+	AuditCountryDelete       = "country.delete"
+	AuditCountryRestore      = "country.restore"
+	AuditCountryPurge        = "country.purge"
+	AuditCountryOverridesSet = "country.overrides.set"
+	AuditCountriesRefresh    = "countries.refresh"
+)
+
+// AuditEvent records a single mutating operation, who made it and what it changed
+type AuditEvent struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	Actor     string      `gorm:"index;not null" json:"actor"`
+	Action    string      `gorm:"index;not null" json:"action"`
+	Target    *string     `gorm:"index" json:"target"` // The affected country, if the action targets one
+	Before    interface{} `gorm:"type:jsonb;serializer:json" json:"before"`
+	After     interface{} `gorm:"type:jsonb;serializer:json" json:"after"`
+	RequestID *string     `gorm:"index" json:"request_id"`
+	CreatedAt time.Time   `gorm:"index" json:"created_at"`
+}
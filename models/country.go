@@ -2,18 +2,53 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Country origins
+const (
+	CountryOriginUpstream = "upstream" // Fetched from the country provider
+	CountryOriginManual   = "manual"   // Created through the API
+)
+
+// Reasons a country was soft-deleted
+const (
+	CountryDeletedManually        = "deleted"          // Deleted through the API
+	CountryDeletedMissingUpstream = "missing_upstream" // Removed by refresh reconciliation
 )
 
 // Country represents the structure of country data stored in the database
 type Country struct {
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	Name            string    `gorm:"unique;not null" json:"name" binding:"required"`
-	Capital         *string   `json:"capital"`
-	Region          *string   `json:"region"`
-	Population      uint64    `gorm:"not null" json:"population" binding:"required"`
-	CurrencyCode    *string   `json:"currency_code" binding:"required"`
-	ExchangeRate    *float64  `json:"exchange_rate"`
-	EstimatedGDP    *float64  `json:"estimated_gdp"`
-	FlagURL         *string   `json:"flag_url"`
+	ID           uint     `gorm:"primaryKey" json:"id"`
+	Name         string   `gorm:"not null" json:"name" binding:"required"` // Unique regardless of case, by idx_countries_name_key
+	Capital      *string  `json:"capital"`
+	Region       *string  `json:"region"`
+	Population   uint64   `gorm:"not null" json:"population" binding:"required"`
+	CurrencyCode *string  `json:"currency_code" binding:"required"`
+	ExchangeRate *float64 `json:"exchange_rate"`
+	EstimatedGDP *float64 `json:"estimated_gdp"`
+	FlagURL      *string  `json:"flag_url"`
+	// AltNames holds other names the country is known by, such as native names and abbreviations, for search
+	AltNames        []string  `gorm:"type:jsonb;serializer:json" json:"alt_names,omitempty"`
 	LastRefreshedAt time.Time `gorm:"autoUpdateTime" json:"last_refreshed_at"`
+	// GDPEstimator and GDPEstimatorParams record how EstimatedGDP was produced
+	GDPEstimator       *string                `json:"gdp_estimator"`
+	GDPEstimatorParams map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"gdp_estimator_params"`
+	// Stale is set when the country has disappeared from the upstream feed
+	Stale bool `gorm:"not null;default:false" json:"stale"`
+	// Origin records whether the country came from upstream or was created through the API.
+	// Only upstream countries are reconciled when they are missing from the feed.
+	Origin    string         `gorm:"not null;default:upstream" json:"origin"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// DeleteReason records why a soft-deleted country was deleted
+	DeleteReason *string `json:"-"`
+	// Currencies lists every currency the country uses; CurrencyCode is the primary one
+	Currencies []Currency `gorm:"many2many:country_currencies;constraint:OnDelete:CASCADE" json:"currencies,omitempty"`
+	// Overrides lists the fields pinned to manually set values
+	Overrides []CountryOverride `gorm:"constraint:OnDelete:CASCADE" json:"overrides,omitempty"`
+	// RateHistory holds recent rates of the primary currency when a query asks for them; it isn't stored
+	RateHistory []ExchangeRate `gorm:"-" json:"rate_history,omitempty"`
+	// Rankings holds the country's rank and percentile by each ranked metric when a query asks for them; it isn't stored
+	Rankings map[string]MetricRank `gorm:"-" json:"rankings,omitempty"`
 }
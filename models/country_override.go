@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// OverridableFields lists the country fields that can be pinned with a CountryOverride
+var OverridableFields = []string{"capital", "region", "population", "currency_code", "exchange_rate", "estimated_gdp", "flag_url"}
+
+// CountryOverride pins a single field of a country to a manually set value that refreshes don't overwrite
+type CountryOverride struct {
+	ID        uint        `gorm:"primaryKey" json:"-"`
+	CountryID uint        `gorm:"uniqueIndex:idx_country_overrides_field;not null" json:"-"`
+	Field     string      `gorm:"uniqueIndex:idx_country_overrides_field;not null" json:"field"`
+	Value     interface{} `gorm:"type:jsonb;serializer:json" json:"value"`
+	SetBy     string      `gorm:"not null" json:"set_by"`
+	Reason    *string     `json:"reason"`
+	SetAt     time.Time   `gorm:"not null" json:"set_at"`
+}
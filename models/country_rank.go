@@ -0,0 +1,12 @@
+package models
+
+// MetricRank is a country's standing by one metric, globally and among countries in the same region.
+// Ranks count from 1 for the largest value, and countries with equal values share a rank. Percentiles are
+// the percentage of countries whose value is at most the country's. Every field is nil when the country
+// has no value for the metric.
+type MetricRank struct {
+	Global           *int64   `json:"global"`
+	Region           *int64   `json:"region"`
+	GlobalPercentile *float64 `json:"global_percentile"`
+	RegionPercentile *float64 `json:"region_percentile"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// CountrySnapshot is a version of a country's row, valid from ValidFrom until ValidTo.
+// The current version of a country has a nil ValidTo.
+type CountrySnapshot struct {
+	ID                 uint                   `gorm:"primaryKey" json:"id"`
+	CountryID          uint                   `gorm:"index" json:"country_id"`
+	RefreshRunID       *uint                  `gorm:"index" json:"refresh_run_id"`
+	Name               string                 `gorm:"index;not null" json:"name"`
+	Capital            *string                `json:"capital"`
+	Region             *string                `json:"region"`
+	Population         uint64                 `json:"population"`
+	CurrencyCode       *string                `json:"currency_code"`
+	ExchangeRate       *float64               `json:"exchange_rate"`
+	EstimatedGDP       *float64               `json:"estimated_gdp"`
+	GDPEstimator       *string                `json:"gdp_estimator"`
+	GDPEstimatorParams map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"gdp_estimator_params"`
+	FlagURL            *string                `json:"flag_url"`
+	LastRefreshedAt    time.Time              `json:"last_refreshed_at"`
+	ValidFrom          time.Time              `gorm:"index;not null" json:"valid_from"`
+	ValidTo            *time.Time             `gorm:"index" json:"valid_to"`
+}
+
+// NewCountrySnapshot creates a snapshot of country that becomes valid at validFrom
+func NewCountrySnapshot(country *Country, validFrom time.Time, runID *uint) CountrySnapshot {
+	return CountrySnapshot{
+		CountryID:          country.ID,
+		RefreshRunID:       runID,
+		Name:               country.Name,
+		Capital:            country.Capital,
+		Region:             country.Region,
+		Population:         country.Population,
+		CurrencyCode:       country.CurrencyCode,
+		ExchangeRate:       country.ExchangeRate,
+		EstimatedGDP:       country.EstimatedGDP,
+		GDPEstimator:       country.GDPEstimator,
+		GDPEstimatorParams: country.GDPEstimatorParams,
+		FlagURL:            country.FlagURL,
+		LastRefreshedAt:    country.LastRefreshedAt,
+		ValidFrom:          validFrom,
+	}
+}
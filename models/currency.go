@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Currency represents a currency used by one or more countries
+type Currency struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Code          string     `gorm:"unique;not null" json:"code"`
+	Name          *string    `json:"name"`
+	Symbol        *string    `json:"symbol"`
+	LatestRate    *float64   `json:"latest_rate"`
+	RateUpdatedAt *time.Time `json:"rate_updated_at"`
+	Countries     []Country  `gorm:"many2many:country_currencies;constraint:OnDelete:CASCADE" json:"countries,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate is a USD exchange rate for a currency as fetched during a refresh
+type ExchangeRate struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CurrencyCode string    `gorm:"index:idx_exchange_rates_code_fetched,priority:1;not null" json:"currency_code"`
+	Rate         float64   `gorm:"not null" json:"rate"`
+	Source       string    `json:"source"`
+	RefreshRunID *uint     `gorm:"index" json:"refresh_run_id"`
+	FetchedAt    time.Time `gorm:"index:idx_exchange_rates_code_fetched,priority:2;not null" json:"fetched_at"`
+}
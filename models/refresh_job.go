@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Refresh job states
+const (
+	RefreshJobQueued    = "queued"
+	RefreshJobRunning   = "running"
+	RefreshJobSucceeded = "succeeded"
+	RefreshJobFailed    = "failed"
+)
+
+// Refresh job phases, reported while a job is running
+const (
+	RefreshPhaseFetchingCountries = "fetching_countries"
+	RefreshPhaseFetchingRates     = "fetching_rates"
+	RefreshPhaseSaving            = "saving"
+	RefreshPhaseGeneratingImage   = "generating_image"
+	RefreshPhaseDone              = "done"
+)
+
+// RefreshJob represents a queued or completed run of the country refresh pipeline
+type RefreshJob struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	State              string     `gorm:"index;not null" json:"state"`
+	Phase              string     `json:"phase"`
+	TotalCountries     int        `json:"total_countries"`
+	ProcessedCountries int        `json:"processed_countries"`
+	Error              *string    `json:"error"`
+	InstanceID         string     `gorm:"index" json:"instance_id"`
+	Trigger            string     `gorm:"not null;default:manual" json:"trigger"` // RefreshTriggerManual or RefreshTriggerScheduled
+	RefreshRunID       *uint      `json:"refresh_run_id"`                         // The run with the detailed counts, once the job succeeds
+	RequestedBy        string     `json:"requested_by"`
+	RequestID          *string    `json:"request_id"`
+	CreatedAt          time.Time  `json:"created_at"`
+	StartedAt          *time.Time `json:"started_at"`
+	FinishedAt         *time.Time `json:"finished_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// Refresh triggers
+const (
+	RefreshTriggerManual    = "manual"
+	RefreshTriggerScheduled = "scheduled"
+)
+
+// Refresh run outcomes
+const (
+	RefreshRunRunning   = "running"
+	RefreshRunSucceeded = "succeeded"
+	RefreshRunFailed    = "failed"
+)
+
+// Country change types
+const (
+	CountryChangeInserted = "inserted"
+	CountryChangeUpdated  = "updated"
+	CountryChangeRemoved  = "removed"
+	CountryChangeStale    = "stale"
+)
+
+// RefreshRun records a single execution of the refresh pipeline
+type RefreshRun struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	RefreshJobID *uint      `gorm:"index" json:"refresh_job_id"`
+	Trigger      string     `gorm:"not null" json:"trigger"`
+	Source       string     `json:"source"`
+	InstanceID   string     `json:"instance_id"`
+	Outcome      string     `gorm:"index;not null" json:"outcome"`
+	Error        *string    `json:"error"`
+	StartedAt    time.Time  `gorm:"index" json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	DurationMs   int64      `json:"duration_ms"`
+	Inserted     int        `json:"inserted"`
+	Updated      int        `json:"updated"`
+	Unchanged    int        `json:"unchanged"`
+	Removed      int        `json:"removed"`
+	// Reconciliation of countries missing from the upstream feed; Removed is SoftDeleted + HardDeleted
+	ReconcilePolicy string `json:"reconcile_policy"`
+	StaleFlagged    int    `json:"stale_flagged"`
+	SoftDeleted     int    `json:"soft_deleted"`
+	HardDeleted     int    `json:"hard_deleted"`
+}
+
+// FieldChange holds the previous and new value of a changed field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// CountryChange records how a single country changed during a refresh run
+type CountryChange struct {
+	ID           uint                   `gorm:"primaryKey" json:"id"`
+	RefreshRunID uint                   `gorm:"index;not null" json:"refresh_run_id"`
+	CountryName  string                 `gorm:"not null" json:"country_name"`
+	ChangeType   string                 `gorm:"not null" json:"change_type"`
+	Changes      map[string]FieldChange `gorm:"type:jsonb;serializer:json" json:"changes"`
+}
@@ -9,4 +9,7 @@ type Status struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
 	TotalCountries  int       `json:"total_countries"`
 	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	LastRefreshedBy string    `json:"last_refreshed_by"`
+	// LastScheduledRunAt is the scheduler tick that last ran, used to stop replicas repeating a tick
+	LastScheduledRunAt *time.Time `json:"last_scheduled_run_at"`
 }
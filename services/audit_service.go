@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditContext identifies who made a change and the request that made it
+type AuditContext struct {
+	Actor     string
+	RequestID string // Empty for changes made outside a request, such as scheduled refreshes
+}
+
+// SchedulerAudit is the AuditContext of scheduled refreshes
+var SchedulerAudit = AuditContext{Actor: "scheduler"}
+
+// recordAudit writes an audit event with tx, so it is only kept if the change it describes is committed
+func recordAudit(tx *gorm.DB, actx AuditContext, action string, target *string, before, after interface{}) error {
+	event := models.AuditEvent{
+		Actor:  actx.Actor,
+		Action: action,
+		Target: target,
+		Before: before,
+		After:  after,
+	}
+	if actx.RequestID != "" {
+		event.RequestID = &actx.RequestID
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record audit event %s: %w", action, err)
+	}
+	return nil
+}
+
+// AuditFilter holds the filters for listing audit events
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+}
+
+// AuditService handles business logic related to the audit log
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// GetEvents retrieves the most recent audit events matching filter, newest first
+func (s *AuditService) GetEvents(filter AuditFilter) ([]models.AuditEvent, error) {
+	query := s.db.Model(&models.AuditEvent{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Target != "" {
+		query = query.Where("LOWER(target) = LOWER(?)", filter.Target)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	events := []models.AuditEvent{}
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	return events, nil
+}
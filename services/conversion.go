@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stage-2/models"
+)
+
+// baseCurrency is the currency every stored rate is quoted against
+const baseCurrency = "USD"
+
+// UnknownCurrencyError is returned when a currency has no stored rates at all
+type UnknownCurrencyError struct {
+	Code string
+}
+
+func (e *UnknownCurrencyError) Error() string {
+	return fmt.Sprintf("no exchange rate has been fetched for %s", e.Code)
+}
+
+// RateUnavailableError is returned when a currency has no rate fetched at or before the requested time
+type RateUnavailableError struct {
+	Code string
+	AsOf time.Time
+}
+
+func (e *RateUnavailableError) Error() string {
+	return fmt.Sprintf("no exchange rate for %s was fetched by %s", e.Code, e.AsOf.Format(time.RFC3339))
+}
+
+// StaleRateError is returned when the latest usable rate of a currency is older than the configured maximum age
+type StaleRateError struct {
+	Code      string
+	FetchedAt time.Time
+	MaxAge    time.Duration
+}
+
+func (e *StaleRateError) Error() string {
+	return fmt.Sprintf("the latest exchange rate for %s was fetched at %s, more than %s earlier", e.Code, e.FetchedAt.Format(time.RFC3339), e.MaxAge)
+}
+
+// RateQuote is the USD rate of a currency used in a conversion
+type RateQuote struct {
+	CurrencyCode string     `json:"currency_code"`
+	Rate         float64    `json:"rate"`       // Units of the currency per USD
+	FetchedAt    *time.Time `json:"fetched_at"` // Nil for USD when no USD rate is stored
+	Source       string     `json:"source"`
+}
+
+// Conversion is the result of converting an amount between two currencies
+type Conversion struct {
+	From   string     `json:"from"`
+	To     string     `json:"to"`
+	Amount float64    `json:"amount"`
+	Result float64    `json:"result"`
+	Rate   float64    `json:"rate"` // Units of To per unit of From
+	AsOf   *time.Time `json:"as_of,omitempty"`
+	// FetchedAt is when the older of the two rates was fetched, and Source lists where they came from
+	FetchedAt *time.Time  `json:"fetched_at"`
+	Source    string      `json:"source"`
+	Legs      []RateQuote `json:"legs"` // The USD rates of From and To
+}
+
+// Convert converts amount from one currency to another through their USD rates.
+// It uses the latest rates fetched at or before asOf, or the latest rates if asOf is nil.
+// It returns an *UnknownCurrencyError, *RateUnavailableError or *StaleRateError if a rate can't be used.
+func (s *ExchangeRateService) Convert(from, to string, amount float64, asOf *time.Time) (*Conversion, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	// Rates are checked for staleness against the conversion time
+	at := time.Now().UTC()
+	if asOf != nil {
+		at = *asOf
+	}
+
+	fromQuote, err := s.quote(from, asOf, at)
+	if err != nil {
+		return nil, err
+	}
+	toQuote, err := s.quote(to, asOf, at)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := toQuote.Rate / fromQuote.Rate
+	conversion := &Conversion{
+		From:   from,
+		To:     to,
+		Amount: amount,
+		Result: amount * rate,
+		Rate:   rate,
+		AsOf:   asOf,
+		Legs:   []RateQuote{*fromQuote, *toQuote},
+	}
+	var sources []string
+	for _, leg := range conversion.Legs {
+		if leg.FetchedAt != nil && (conversion.FetchedAt == nil || leg.FetchedAt.Before(*conversion.FetchedAt)) {
+			conversion.FetchedAt = leg.FetchedAt
+		}
+		if leg.Source != "" && (len(sources) == 0 || sources[0] != leg.Source) {
+			sources = append(sources, leg.Source)
+		}
+	}
+	conversion.Source = strings.Join(sources, ", ")
+	return conversion, nil
+}
+
+// quote returns the latest USD rate of code fetched at or before asOf, checking its age against at
+func (s *ExchangeRateService) quote(code string, asOf *time.Time, at time.Time) (*RateQuote, error) {
+	query := s.db.Where("currency_code = ?", code)
+	if asOf != nil {
+		query = query.Where("fetched_at <= ?", *asOf)
+	}
+	var rates []models.ExchangeRate
+	if err := query.Order("fetched_at DESC, id DESC").Limit(1).Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up exchange rate for %s: %w", code, err)
+	}
+
+	if len(rates) == 0 {
+		if code == baseCurrency {
+			return &RateQuote{CurrencyCode: code, Rate: 1}, nil
+		}
+		var known int64
+		if err := s.db.Model(&models.ExchangeRate{}).Where("currency_code = ?", code).Limit(1).Count(&known).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up currency %s: %w", code, err)
+		}
+		if known == 0 || asOf == nil {
+			return nil, &UnknownCurrencyError{Code: code}
+		}
+		return nil, &RateUnavailableError{Code: code, AsOf: *asOf}
+	}
+
+	rate := rates[0]
+	if s.rateConfig.MaxAge > 0 && at.Sub(rate.FetchedAt) > s.rateConfig.MaxAge {
+		return nil, &StaleRateError{Code: code, FetchedAt: rate.FetchedAt, MaxAge: s.rateConfig.MaxAge}
+	}
+	if rate.Rate <= 0 {
+		return nil, fmt.Errorf("stored exchange rate for %s is not positive", code)
+	}
+	return &RateQuote{CurrencyCode: code, Rate: rate.Rate, FetchedAt: &rate.FetchedAt, Source: rate.Source}, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+
+	"stage-2/models"
+)
+
+// CountryMetrics holds the metrics compared between countries
+type CountryMetrics struct {
+	Population   uint64   `json:"population"`
+	EstimatedGDP *float64 `json:"estimated_gdp"`
+	GDPPerCapita *float64 `json:"gdp_per_capita"`
+	ExchangeRate *float64 `json:"exchange_rate"`
+}
+
+// MetricDelta is the difference between a country's metric and the baseline country's
+type MetricDelta struct {
+	Absolute *float64 `json:"absolute"`
+	Percent  *float64 `json:"percent"` // Relative to the baseline value; nil if it is zero
+}
+
+// ComparedCountry is one entry of a comparison, in the order the names were given.
+// Found is false, and only Query and Error are set, if no country has the name.
+type ComparedCountry struct {
+	Query        string                       `json:"query"`
+	Found        bool                         `json:"found"`
+	Error        string                       `json:"error,omitempty"`
+	Name         string                       `json:"name,omitempty"`
+	Region       *string                      `json:"region,omitempty"`
+	CurrencyCode *string                      `json:"currency_code,omitempty"`
+	FlagURL      *string                      `json:"flag_url,omitempty"`
+	Metrics      *CountryMetrics              `json:"metrics,omitempty"`
+	Ranks        map[string]models.MetricRank `json:"ranks,omitempty"`
+	Deltas       map[string]MetricDelta       `json:"deltas,omitempty"` // Relative to the baseline; nil without one
+}
+
+// CountryComparison compares countries side by side. Baseline is the country of the first name, which deltas
+// are relative to; it is nil, and no deltas are computed, if that name doesn't match a country.
+type CountryComparison struct {
+	Baseline  *string           `json:"baseline"`
+	Countries []ComparedCountry `json:"countries"`
+}
+
+// CompareCountries looks up the named countries and compares their metrics and ranks.
+// Names that don't match a country are reported in their entry rather than failing the comparison.
+func (s *CountryService) CompareCountries(names []string) (*CountryComparison, error) {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = nameKey(name)
+	}
+	var countries []models.Country
+	if err := s.db.Where("LOWER(name) IN ?", keys).Find(&countries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch countries to compare: %w", err)
+	}
+	byKey := make(map[string]*models.Country, len(countries))
+	ids := make([]uint, len(countries))
+	for i := range countries {
+		byKey[nameKey(countries[i].Name)] = &countries[i]
+		ids[i] = countries[i].ID
+	}
+
+	ranks, err := s.loadRanks(ids, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &CountryComparison{Countries: make([]ComparedCountry, len(names))}
+	var baseline *CountryMetrics
+	if country, ok := byKey[keys[0]]; ok {
+		baseline = countryMetrics(country)
+		comparison.Baseline = &country.Name
+	}
+	for i, name := range names {
+		country, ok := byKey[keys[i]]
+		if !ok {
+			comparison.Countries[i] = ComparedCountry{Query: name, Error: "Country not found"}
+			continue
+		}
+
+		metrics := countryMetrics(country)
+		comparison.Countries[i] = ComparedCountry{
+			Query:        name,
+			Found:        true,
+			Name:         country.Name,
+			Region:       country.Region,
+			CurrencyCode: country.CurrencyCode,
+			FlagURL:      country.FlagURL,
+			Metrics:      metrics,
+			Ranks:        ranks[country.ID],
+			Deltas:       metricDeltas(metrics, baseline),
+		}
+	}
+	return comparison, nil
+}
+
+// countryMetrics collects the compared metrics of country
+func countryMetrics(country *models.Country) *CountryMetrics {
+	metrics := &CountryMetrics{
+		Population:   country.Population,
+		EstimatedGDP: country.EstimatedGDP,
+		ExchangeRate: country.ExchangeRate,
+	}
+	if country.EstimatedGDP != nil && country.Population > 0 {
+		metrics.GDPPerCapita = floatPtr(*country.EstimatedGDP / float64(country.Population))
+	}
+	return metrics
+}
+
+// metricDeltas returns the differences between metrics and the baseline's, or nil without a baseline
+func metricDeltas(metrics, baseline *CountryMetrics) map[string]MetricDelta {
+	if baseline == nil {
+		return nil
+	}
+	return map[string]MetricDelta{
+		"population":     metricDelta(floatPtr(float64(metrics.Population)), floatPtr(float64(baseline.Population))),
+		"estimated_gdp":  metricDelta(metrics.EstimatedGDP, baseline.EstimatedGDP),
+		"gdp_per_capita": metricDelta(metrics.GDPPerCapita, baseline.GDPPerCapita),
+		"exchange_rate":  metricDelta(metrics.ExchangeRate, baseline.ExchangeRate),
+	}
+}
+
+// metricDelta returns the difference between value and base; both parts are nil if either is nil
+func metricDelta(value, base *float64) MetricDelta {
+	if value == nil || base == nil {
+		return MetricDelta{}
+	}
+	delta := MetricDelta{Absolute: floatPtr(*value - *base)}
+	if *base != 0 {
+		delta.Percent = floatPtr((*value - *base) / *base * 100)
+	}
+	return delta
+}
+
+// floatPtr returns a pointer to v
+func floatPtr(v float64) *float64 {
+	return &v
+}
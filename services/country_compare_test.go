@@ -0,0 +1,92 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"stage-2/models"
+)
+
+func TestMetricDeltas(t *testing.T) {
+	baseline := &CountryMetrics{Population: 200, EstimatedGDP: floatPtr(1000), GDPPerCapita: floatPtr(5), ExchangeRate: floatPtr(0)}
+
+	tests := []struct {
+		name     string
+		metrics  *CountryMetrics
+		baseline *CountryMetrics
+		want     map[string]MetricDelta
+	}{
+		{name: "no baseline", metrics: baseline, baseline: nil, want: nil},
+		{
+			name:     "the baseline itself",
+			metrics:  baseline,
+			baseline: baseline,
+			want: map[string]MetricDelta{
+				"population":     {Absolute: floatPtr(0), Percent: floatPtr(0)},
+				"estimated_gdp":  {Absolute: floatPtr(0), Percent: floatPtr(0)},
+				"gdp_per_capita": {Absolute: floatPtr(0), Percent: floatPtr(0)},
+				"exchange_rate":  {Absolute: floatPtr(0)},
+			},
+		},
+		{
+			name:     "another country",
+			metrics:  &CountryMetrics{Population: 50, EstimatedGDP: floatPtr(1500), ExchangeRate: floatPtr(2)},
+			baseline: baseline,
+			want: map[string]MetricDelta{
+				"population":     {Absolute: floatPtr(-150), Percent: floatPtr(-75)},
+				"estimated_gdp":  {Absolute: floatPtr(500), Percent: floatPtr(50)},
+				"gdp_per_capita": {},
+				"exchange_rate":  {Absolute: floatPtr(2)}, // No percentage of a zero rate
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metricDeltas(tt.metrics, tt.baseline); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metricDeltas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareCountriesBaseline(t *testing.T) {
+	s := newReplayCountryService(t, openTestDB(t))
+	t.Chdir(t.TempDir())
+	if _, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}}); err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		names        []string
+		wantBaseline *string
+	}{
+		{"first name", []string{"france", "Nigeria"}, strPtr("France")},
+		{"first name not found", []string{"Atlantis", "Nigeria", "France"}, nil},
+		{"first name found after a missing one", []string{"Nigeria", "Atlantis", "France"}, strPtr("Nigeria")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison, err := s.CompareCountries(tt.names)
+			if err != nil {
+				t.Fatalf("CompareCountries() error = %v", err)
+			}
+			if !equalPtr(comparison.Baseline, tt.wantBaseline) {
+				t.Errorf("baseline = %v, want %v", derefString(comparison.Baseline), derefString(tt.wantBaseline))
+			}
+			for _, country := range comparison.Countries {
+				if !country.Found {
+					continue
+				}
+				if tt.wantBaseline == nil && country.Deltas != nil {
+					t.Errorf("%s has deltas %v without a baseline", country.Name, country.Deltas)
+				}
+				if tt.wantBaseline != nil && country.Name == *tt.wantBaseline {
+					if delta := country.Deltas["population"]; delta.Absolute == nil || *delta.Absolute != 0 {
+						t.Errorf("baseline %s has population delta %v, want 0", country.Name, derefFloat(delta.Absolute))
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CountryFilter holds the filters and sort order for listing countries
+type CountryFilter struct {
+	Regions    []string   // Match any of these regions
+	Currencies []string   // Match countries using any of these currencies
+	Sort       string     // e.g., region,-estimated_gdp,name; see ValidateSort
+	Nulls      string     // "first", "last", or empty for the Postgres default
+	AsOf       *time.Time // If set, query the versions that were current at this time
+
+	// Inclusive bounds; nil means unbounded
+	PopulationMin   *uint64
+	PopulationMax   *uint64
+	GDPMin          *float64
+	GDPMax          *float64
+	ExchangeRateMin *float64
+	ExchangeRateMax *float64
+
+	HasCurrency *bool // If set, only countries with (true) or without (false) a primary currency
+	HasRate     *bool // If set, only countries with (true) or without (false) an exchange rate
+}
+
+// applyCountryFilter adds the conditions of filter to a query over countries or, with AsOf, their snapshots
+func applyCountryFilter(query *gorm.DB, filter CountryFilter) *gorm.DB {
+	if len(filter.Regions) > 0 {
+		query = query.Where("LOWER(region) IN ?", lowerAll(filter.Regions))
+	}
+	if len(filter.Currencies) > 0 {
+		if filter.AsOf != nil {
+			// Snapshots only record the primary currency
+			query = query.Where("LOWER(currency_code) IN ?", lowerAll(filter.Currencies))
+		} else {
+			// Match any of the country's currencies, not just the primary one
+			query = query.Where(`EXISTS (
+				SELECT 1 FROM country_currencies cc
+				JOIN currencies cur ON cur.id = cc.currency_id
+				WHERE cc.country_id = countries.id AND LOWER(cur.code) IN ?
+			)`, lowerAll(filter.Currencies))
+		}
+	}
+
+	if filter.PopulationMin != nil {
+		query = query.Where("population >= ?", *filter.PopulationMin)
+	}
+	if filter.PopulationMax != nil {
+		query = query.Where("population <= ?", *filter.PopulationMax)
+	}
+	if filter.GDPMin != nil {
+		query = query.Where("estimated_gdp >= ?", *filter.GDPMin)
+	}
+	if filter.GDPMax != nil {
+		query = query.Where("estimated_gdp <= ?", *filter.GDPMax)
+	}
+	if filter.ExchangeRateMin != nil {
+		query = query.Where("exchange_rate >= ?", *filter.ExchangeRateMin)
+	}
+	if filter.ExchangeRateMax != nil {
+		query = query.Where("exchange_rate <= ?", *filter.ExchangeRateMax)
+	}
+
+	if filter.HasCurrency != nil {
+		if *filter.HasCurrency {
+			query = query.Where("currency_code IS NOT NULL")
+		} else {
+			query = query.Where("currency_code IS NULL")
+		}
+	}
+	if filter.HasRate != nil {
+		if *filter.HasRate {
+			query = query.Where("exchange_rate IS NOT NULL")
+		} else {
+			query = query.Where("exchange_rate IS NULL")
+		}
+	}
+	return query
+}
+
+// lowerAll returns the values in lower case
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(v)
+	}
+	return lowered
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"stage-2/config"
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// overrideEstimator is recorded as the GDP estimator of countries whose estimated_gdp is overridden
+const overrideEstimator = "override"
+
+// currencyCodePattern matches an ISO 4217 currency code
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ValidCurrencyCode reports whether code is a 3-letter currency code, ignoring case
+func ValidCurrencyCode(code string) bool {
+	return currencyCodePattern.MatchString(strings.ToUpper(code))
+}
+
+// ValidateOverrides checks that every field can be overridden and that its value has the right type.
+// It returns nil if the overrides are valid.
+func ValidateOverrides(overrides map[string]interface{}) map[string]string {
+	allowed := make(map[string]bool, len(models.OverridableFields))
+	for _, field := range models.OverridableFields {
+		allowed[field] = true
+	}
+
+	errs := make(map[string]string)
+	for field, value := range overrides {
+		if !allowed[field] {
+			errs[field] = "cannot be overridden; allowed fields are " + strings.Join(models.OverridableFields, ", ")
+			continue
+		}
+		switch field {
+		case "capital", "region", "flag_url":
+			if _, ok := value.(string); !ok && value != nil {
+				errs[field] = "must be a string or null"
+			}
+		case "currency_code":
+			if s, ok := value.(string); ok {
+				if !ValidCurrencyCode(s) {
+					errs[field] = "must be a 3-letter currency code or null"
+				}
+			} else if value != nil {
+				errs[field] = "must be a 3-letter currency code or null"
+			}
+		case "population":
+			if f, ok := value.(float64); !ok || f < 0 || f != math.Trunc(f) {
+				errs[field] = "must be a non-negative integer"
+			}
+		case "exchange_rate":
+			if f, ok := value.(float64); (ok && f <= 0) || (!ok && value != nil) {
+				errs[field] = "must be a positive number or null"
+			}
+		case "estimated_gdp":
+			if f, ok := value.(float64); (ok && f < 0) || (!ok && value != nil) {
+				errs[field] = "must be a non-negative number or null"
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// applyOverrides sets each of the given fields of country to its overridden value, if it has one
+func applyOverrides(country *models.Country, overrides map[string]interface{}, fields ...string) {
+	for _, field := range fields {
+		value, ok := overrides[field]
+		if !ok {
+			continue
+		}
+		switch field {
+		case "capital":
+			country.Capital = overrideString(value)
+		case "region":
+			country.Region = overrideString(value)
+		case "flag_url":
+			country.FlagURL = overrideString(value)
+		case "currency_code":
+			country.CurrencyCode = overrideString(value)
+		case "population":
+			if f, ok := value.(float64); ok {
+				country.Population = uint64(f)
+			}
+		case "exchange_rate":
+			country.ExchangeRate = overrideFloat(value)
+		case "estimated_gdp":
+			country.EstimatedGDP = overrideFloat(value)
+			estimator := overrideEstimator
+			country.GDPEstimator = &estimator
+			country.GDPEstimatorParams = nil
+		}
+	}
+}
+
+// gdpInputsChanged reports whether after has a different population or exchange rate than before
+func gdpInputsChanged(before, after *models.Country) bool {
+	return before.Population != after.Population || derefFloat(before.ExchangeRate) != derefFloat(after.ExchangeRate)
+}
+
+// storedGDPPerCapita returns the GDP per capita the stored estimate of country was computed from, if any,
+// since it isn't kept anywhere else between refreshes
+func storedGDPPerCapita(country *models.Country) *float64 {
+	if country.GDPEstimator == nil || *country.GDPEstimator != config.GDPEstimatorPerCapita {
+		return nil
+	}
+	if perCapita, ok := country.GDPEstimatorParams["gdp_per_capita"].(float64); ok {
+		return &perCapita
+	}
+	return nil
+}
+
+// overrideString converts a stored override value to a nullable string
+func overrideString(value interface{}) *string {
+	if s, ok := value.(string); ok {
+		return &s
+	}
+	return nil
+}
+
+// overrideFloat converts a stored override value to a nullable number
+func overrideFloat(value interface{}) *float64 {
+	if f, ok := value.(float64); ok {
+		return &f
+	}
+	return nil
+}
+
+// loadOverrides returns the overridden field values of every country, keyed by nameKey
+func (s *CountryService) loadOverrides() (map[string]map[string]interface{}, error) {
+	// Soft-deleted countries are included, since a refresh may bring them back
+	var countries []models.Country
+	err := s.db.Unscoped().Preload("Overrides").
+		Where("id IN (SELECT country_id FROM country_overrides)").
+		Find(&countries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load country overrides: %w", err)
+	}
+
+	overrides := make(map[string]map[string]interface{}, len(countries))
+	for _, country := range countries {
+		values := make(map[string]interface{}, len(country.Overrides))
+		for _, override := range country.Overrides {
+			values[override.Field] = override.Value
+		}
+		overrides[nameKey(country.Name)] = values
+	}
+	return overrides, nil
+}
+
+// SetOverrides replaces every override of the named country and applies the new values to it immediately.
+// Unless estimated_gdp is overridden, it is estimated again if the population or exchange rate changes.
+// Fields whose override is removed keep their current value until the next refresh.
+// It returns gorm.ErrRecordNotFound if the country doesn't exist.
+// The actor of actx is recorded as the setter of every override.
+func (s *CountryService) SetOverrides(actx AuditContext, name string, overrides map[string]interface{}, reason *string) (*models.Country, error) {
+	if code, ok := overrides["currency_code"].(string); ok {
+		overrides["currency_code"] = strings.ToUpper(code)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var country models.Country
+		if err := tx.Where("LOWER(name) = LOWER(?)", name).Take(&country).Error; err != nil {
+			return err
+		}
+
+		var previous []models.CountryOverride
+		if err := tx.Where("country_id = ?", country.ID).Find(&previous).Error; err != nil {
+			return fmt.Errorf("failed to load overrides of country %s: %w", country.Name, err)
+		}
+		previousValues := make(map[string]interface{}, len(previous))
+		for _, override := range previous {
+			previousValues[override.Field] = override.Value
+		}
+		err := recordAudit(tx, actx, models.AuditCountryOverridesSet, &country.Name,
+			map[string]interface{}{"overrides": previousValues},
+			map[string]interface{}{"overrides": overrides, "reason": reason})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("country_id = ?", country.ID).Delete(&models.CountryOverride{}).Error; err != nil {
+			return fmt.Errorf("failed to clear overrides of country %s: %w", country.Name, err)
+		}
+		if len(overrides) == 0 {
+			return nil
+		}
+
+		now := time.Now().UTC()
+		rows := make([]models.CountryOverride, 0, len(overrides))
+		for _, field := range models.OverridableFields {
+			if value, ok := overrides[field]; ok {
+				rows = append(rows, models.CountryOverride{
+					CountryID: country.ID,
+					Field:     field,
+					Value:     value,
+					SetBy:     actx.Actor,
+					Reason:    reason,
+					SetAt:     now,
+				})
+			}
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to save overrides of country %s: %w", country.Name, err)
+		}
+
+		before := country
+		applyOverrides(&country, overrides, models.OverridableFields...)
+		// A new primary currency brings its latest known rate, unless the rate is overridden too
+		if _, ok := overrides["currency_code"]; ok {
+			if _, pinned := overrides["exchange_rate"]; !pinned {
+				rate, err := latestRate(tx, country.CurrencyCode)
+				if err != nil {
+					return err
+				}
+				country.ExchangeRate = rate
+			}
+		}
+		// Estimated GDP follows the overridden inputs, as the next refresh would compute it
+		if _, pinned := overrides["estimated_gdp"]; !pinned && gdpInputsChanged(&before, &country) {
+			s.estimateGDP(&country, storedGDPPerCapita(&before), now)
+		}
+		if len(diffCountries(&before, &country)) == 0 {
+			return nil
+		}
+		err = tx.Model(&country).Select("capital", "region", "population", "currency_code", "exchange_rate",
+			"estimated_gdp", "gdp_estimator", "gdp_estimator_params", "flag_url").Updates(&country).Error
+		if err != nil {
+			return fmt.Errorf("failed to apply overrides to country %s: %w", country.Name, err)
+		}
+
+		// Start a new version so as_of queries see when the override took effect
+		if err := closeSnapshot(tx, country.Name, now); err != nil {
+			return err
+		}
+		snapshot := models.NewCountrySnapshot(&country, now, nil)
+		if err := tx.Create(&snapshot).Error; err != nil {
+			return fmt.Errorf("failed to record snapshot of country %s: %w", country.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCountryByName(name, nil, CountryProjection{})
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"testing"
+
+	"stage-2/models"
+)
+
+func TestValidateOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		want      map[string]string // Fields with errors; nil if valid
+	}{
+		{"empty", map[string]interface{}{}, nil},
+		{"strings", map[string]interface{}{"capital": "Abuja", "region": "Africa", "flag_url": "https://flagcdn.com/ng.svg"}, nil},
+		{"nulls", map[string]interface{}{"capital": nil, "region": nil, "flag_url": nil, "currency_code": nil, "exchange_rate": nil, "estimated_gdp": nil}, nil},
+		{"lowercase currency code", map[string]interface{}{"currency_code": "ngn"}, nil},
+		{"numbers", map[string]interface{}{"population": 206139589.0, "exchange_rate": 1600.23, "estimated_gdp": 0.0}, nil},
+		{"unknown field", map[string]interface{}{"name": "Naija"}, map[string]string{"name": ""}},
+		{"capital not a string", map[string]interface{}{"capital": 42.0}, map[string]string{"capital": "must be a string or null"}},
+		{"currency code too long", map[string]interface{}{"currency_code": "NGNX"}, map[string]string{"currency_code": "must be a 3-letter currency code or null"}},
+		{"currency code not a string", map[string]interface{}{"currency_code": 566.0}, map[string]string{"currency_code": "must be a 3-letter currency code or null"}},
+		{"population null", map[string]interface{}{"population": nil}, map[string]string{"population": "must be a non-negative integer"}},
+		{"population negative", map[string]interface{}{"population": -1.0}, map[string]string{"population": "must be a non-negative integer"}},
+		{"population fractional", map[string]interface{}{"population": 1.5}, map[string]string{"population": "must be a non-negative integer"}},
+		{"exchange rate zero", map[string]interface{}{"exchange_rate": 0.0}, map[string]string{"exchange_rate": "must be a positive number or null"}},
+		{"exchange rate string", map[string]interface{}{"exchange_rate": "1600"}, map[string]string{"exchange_rate": "must be a positive number or null"}},
+		{"estimated gdp negative", map[string]interface{}{"estimated_gdp": -5.0}, map[string]string{"estimated_gdp": "must be a non-negative number or null"}},
+		{
+			"several errors",
+			map[string]interface{}{"capital": true, "population": "many", "region": "Africa"},
+			map[string]string{"capital": "must be a string or null", "population": "must be a non-negative integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateOverrides(tt.overrides)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("ValidateOverrides() = %v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ValidateOverrides() = %v, want errors for %v", got, tt.want)
+			}
+			for field, msg := range tt.want {
+				gotMsg, ok := got[field]
+				if !ok {
+					t.Errorf("ValidateOverrides() has no error for %s: %v", field, got)
+				} else if msg != "" && gotMsg != msg {
+					t.Errorf("ValidateOverrides()[%q] = %q, want %q", field, gotMsg, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	capital, rate := "Lagos", 1500.0
+	country := models.Country{Name: "Nigeria", Capital: &capital, Population: 1, ExchangeRate: &rate,
+		GDPEstimatorParams: map[string]interface{}{"multiplier": 1500.0}}
+	overrides := map[string]interface{}{
+		"capital":       "Abuja",
+		"population":    206139589.0,
+		"exchange_rate": nil,
+		"estimated_gdp": 4.5e11,
+	}
+
+	// Only the listed fields are applied
+	applyOverrides(&country, overrides, "capital", "population")
+	if *country.Capital != "Abuja" || country.Population != 206139589 {
+		t.Errorf("capital = %s, population = %d; want Abuja and 206139589", *country.Capital, country.Population)
+	}
+	if country.ExchangeRate == nil {
+		t.Fatalf("exchange_rate was cleared before it was applied")
+	}
+
+	applyOverrides(&country, overrides, "exchange_rate", "estimated_gdp")
+	if country.ExchangeRate != nil {
+		t.Errorf("exchange_rate = %v, want nil", *country.ExchangeRate)
+	}
+	if country.EstimatedGDP == nil || *country.EstimatedGDP != 4.5e11 {
+		t.Errorf("estimated_gdp = %v, want 4.5e11", derefFloat(country.EstimatedGDP))
+	}
+	if country.GDPEstimator == nil || *country.GDPEstimator != overrideEstimator {
+		t.Errorf("gdp_estimator = %v, want %s", derefString(country.GDPEstimator), overrideEstimator)
+	}
+	if country.GDPEstimatorParams != nil {
+		t.Errorf("gdp_estimator_params = %v, want nil", country.GDPEstimatorParams)
+	}
+
+	// Fields without an override keep their value
+	applyOverrides(&country, nil, "capital")
+	if *country.Capital != "Abuja" {
+		t.Errorf("capital = %s after applying no overrides, want Abuja", *country.Capital)
+	}
+}
+
+func TestSetOverridesEstimatesGDP(t *testing.T) {
+	db := openTestDB(t)
+	s := newReplayCountryService(t, db)
+	t.Chdir(t.TempDir())
+	if _, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}}); err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		wantGDP   *float64
+	}{
+		{"population", map[string]interface{}{"population": 210000000.0}, fixedGDP(210000000, 1600.23)},
+		{"exchange rate", map[string]interface{}{"exchange_rate": 1500.0}, fixedGDP(206139587, 1500)},
+		{"currency", map[string]interface{}{"currency_code": "USD"}, fixedGDP(206139587, 1)},
+		{"population and gdp", map[string]interface{}{"population": 1.0, "estimated_gdp": 5e11}, floatPtr(5e11)},
+		{"capital only", map[string]interface{}{"capital": "Lagos"}, fixedGDP(206139587, 1600.23)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each case replaces the overrides of the last, so start from the refreshed values
+			if _, err := s.SetOverrides(AuditContext{Actor: "test"}, "Nigeria", map[string]interface{}{}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			country, err := s.SetOverrides(AuditContext{Actor: "test"}, "Nigeria", tt.overrides, nil)
+			if err != nil {
+				t.Fatalf("SetOverrides() error = %v", err)
+			}
+			if !equalPtr(country.EstimatedGDP, tt.wantGDP) {
+				t.Errorf("estimated_gdp = %v, want %v", derefFloat(country.EstimatedGDP), derefFloat(tt.wantGDP))
+			}
+
+			var snapshot models.CountrySnapshot
+			if err := db.Where("name = ? AND valid_to IS NULL", "Nigeria").Take(&snapshot).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !equalPtr(snapshot.EstimatedGDP, tt.wantGDP) {
+				t.Errorf("snapshot estimated_gdp = %v, want %v", derefFloat(snapshot.EstimatedGDP), derefFloat(tt.wantGDP))
+			}
+		})
+	}
+}
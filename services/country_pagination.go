@@ -0,0 +1,213 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded or was issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Pagination selects a page of results, either by page number or by a cursor from a previous page
+type Pagination struct {
+	Page    int    // 1-based page number, used when Cursor is empty
+	PerPage int    // Page size; 0 returns every row
+	Cursor  string // Opaque cursor from CountryPage.NextCursor; takes precedence over Page
+}
+
+// CountryPage is a page of countries along with the total number of matching countries
+type CountryPage struct {
+	Countries  []models.Country
+	Total      int64
+	NextCursor string // Empty on the last page
+}
+
+// sortKey is a single column of a sort order
+type sortKey struct {
+	Column string
+	Desc   bool
+	Nulls  string // NullsFirst, NullsLast, or empty for the Postgres default
+}
+
+// nullsFirst reports whether NULLs sort before other values for the key
+func (k sortKey) nullsFirst() bool {
+	if k.Nulls == "" {
+		return k.Desc // Postgres sorts NULLs as larger than any value
+	}
+	return k.Nulls == NullsFirst
+}
+
+// orderClause returns the ORDER BY clause for keys, with idColumn as the final tiebreaker
+func orderClause(keys []sortKey, idColumn string) string {
+	parts := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		switch key.Nulls {
+		case NullsFirst:
+			direction += " NULLS FIRST"
+		case NullsLast:
+			direction += " NULLS LAST"
+		}
+		parts = append(parts, key.Column+" "+direction)
+	}
+	return strings.Join(append(parts, idColumn+" ASC"), ", ")
+}
+
+// sortSignature identifies a sort order, so cursors can't be reused with a different one
+func sortSignature(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Column
+		if key.Desc {
+			parts[i] = "-" + key.Column
+		}
+		if key.Nulls != "" {
+			parts[i] += " nulls " + key.Nulls
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// countryCursor is the decoded form of a pagination cursor: the sort values and ID of the last row of a page
+type countryCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	ID     uint          `json:"id"`
+}
+
+// encodeCursor returns the cursor that continues after country
+func encodeCursor(keys []sortKey, country *models.Country) string {
+	cursor := countryCursor{Sort: sortSignature(keys), ID: country.ID}
+	for _, key := range keys {
+		cursor.Values = append(cursor.Values, countrySortValue(country, key.Column))
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor decodes a cursor and checks that it was issued for keys
+func decodeCursor(keys []sortKey, encoded string) (*countryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor countryCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sortSignature(keys) || len(cursor.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	for i, key := range keys {
+		value, ok := parseSortValue(key.Column, cursor.Values[i])
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		cursor.Values[i] = value
+	}
+	return &cursor, nil
+}
+
+// countrySortValue returns the value of a sortable column of country, or nil if it is NULL
+func countrySortValue(country *models.Country, column string) interface{} {
+	switch column {
+	case "name":
+		return country.Name
+	case "capital":
+		return derefString(country.Capital)
+	case "region":
+		return derefString(country.Region)
+	case "population":
+		return country.Population
+	case "currency_code":
+		return derefString(country.CurrencyCode)
+	case "exchange_rate":
+		return derefFloat(country.ExchangeRate)
+	case "estimated_gdp":
+		return derefFloat(country.EstimatedGDP)
+	case "last_refreshed_at":
+		return country.LastRefreshedAt.Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+// parseSortValue converts a sort value decoded from JSON back to the Go type of its column
+func parseSortValue(column string, value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, true
+	}
+	switch column {
+	case "name", "capital", "region", "currency_code":
+		s, ok := value.(string)
+		return s, ok
+	case "population":
+		f, ok := value.(float64)
+		return uint64(f), ok && f >= 0
+	case "exchange_rate", "estimated_gdp":
+		f, ok := value.(float64)
+		return f, ok
+	case "last_refreshed_at":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	}
+	return nil, false
+}
+
+// afterCursor restricts query to the rows that sort after cursor. The rows are compared key by key,
+// so the condition is an OR of "equal on the earlier keys and after on this one" terms.
+func afterCursor(query *gorm.DB, keys []sortKey, idColumn string, cursor *countryCursor) *gorm.DB {
+	var (
+		terms []string
+		args  []interface{}
+		// equal and equalArgs accumulate "equal on every earlier key"
+		equal     []string
+		equalArgs []interface{}
+	)
+	for i, key := range keys {
+		value := cursor.Values[i]
+		var after string
+		var afterArgs []interface{}
+		switch {
+		case value == nil && key.nullsFirst():
+			after = key.Column + " IS NOT NULL"
+		case value == nil:
+			after = "" // Nothing sorts after NULL when NULLs come last
+		default:
+			op := ">"
+			if key.Desc {
+				op = "<"
+			}
+			after = fmt.Sprintf("%s %s ?", key.Column, op)
+			afterArgs = []interface{}{value}
+			if !key.nullsFirst() {
+				after = "(" + after + " OR " + key.Column + " IS NULL)"
+			}
+		}
+		if after != "" {
+			terms = append(terms, strings.Join(append(append([]string{}, equal...), after), " AND "))
+			args = append(append(args, equalArgs...), afterArgs...)
+		}
+		equal = append(equal, key.Column+" IS NOT DISTINCT FROM ?")
+		equalArgs = append(equalArgs, value)
+	}
+	// Rows equal on every key are ordered by ID
+	terms = append(terms, strings.Join(append(equal, idColumn+" > ?"), " AND "))
+	args = append(append(args, equalArgs...), cursor.ID)
+
+	return query.Where("(("+strings.Join(terms, ") OR (")+"))", args...)
+}
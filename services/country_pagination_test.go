@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+	gormtests "gorm.io/gorm/utils/tests"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	refreshed := time.Date(2025, 10, 22, 18, 0, 0, 123456789, time.UTC)
+	country := &models.Country{
+		ID:              42,
+		Name:            "Burundi",
+		Region:          strPtr("Africa"),
+		Population:      11890781,
+		ExchangeRate:    floatPtr(2952.5),
+		LastRefreshedAt: refreshed,
+	}
+
+	tests := []struct {
+		name string
+		keys []sortKey
+		want []interface{}
+	}{
+		{"name", []sortKey{{Column: "name"}}, []interface{}{"Burundi"}},
+		{"descending population", []sortKey{{Column: "population", Desc: true}}, []interface{}{uint64(11890781)}},
+		{"null value", []sortKey{{Column: "capital"}, {Column: "estimated_gdp", Nulls: NullsFirst}}, []interface{}{nil, nil}},
+		{
+			"several keys",
+			[]sortKey{{Column: "region"}, {Column: "exchange_rate", Desc: true}, {Column: "last_refreshed_at"}},
+			[]interface{}{"Africa", 2952.5, refreshed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeCursor(tt.keys, encodeCursor(tt.keys, country))
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if cursor.ID != country.ID {
+				t.Errorf("cursor ID = %d, want %d", cursor.ID, country.ID)
+			}
+			if !reflect.DeepEqual(cursor.Values, tt.want) {
+				t.Errorf("cursor values = %#v, want %#v", cursor.Values, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	byName := []sortKey{{Column: "name"}}
+	country := &models.Country{ID: 1, Name: "Angola", Population: 32866268}
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name    string
+		keys    []sortKey
+		encoded string
+	}{
+		{"not base64", byName, "not a cursor!"},
+		{"not JSON", byName, encode("Angola")},
+		{"different column", []sortKey{{Column: "population"}}, encodeCursor(byName, country)},
+		{"different direction", []sortKey{{Column: "name", Desc: true}}, encodeCursor(byName, country)},
+		{"different nulls", []sortKey{{Column: "name", Nulls: NullsLast}}, encodeCursor(byName, country)},
+		{"missing value", byName, encode(`{"s":"name","v":[],"id":1}`)},
+		{"wrong value type", byName, encode(`{"s":"name","v":[7],"id":1}`)},
+		{"negative population", []sortKey{{Column: "population"}}, encode(`{"s":"population","v":[-1],"id":1}`)},
+		{"bad time", []sortKey{{Column: "last_refreshed_at"}}, encode(`{"s":"last_refreshed_at","v":["yesterday"],"id":1}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.keys, tt.encoded); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestOrderClause(t *testing.T) {
+	keys := []sortKey{{Column: "region"}, {Column: "estimated_gdp", Desc: true, Nulls: NullsLast}, {Column: "capital", Nulls: NullsFirst}}
+	want := "region ASC, estimated_gdp DESC NULLS LAST, capital ASC NULLS FIRST, id ASC"
+	if got := orderClause(keys, "id"); got != want {
+		t.Errorf("orderClause() = %q, want %q", got, want)
+	}
+}
+
+func TestAfterCursor(t *testing.T) {
+	db, err := gorm.Open(gormtests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     []sortKey
+		values   []interface{}
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			// NULLs sort last in ascending order, so they come after every value
+			"ascending",
+			[]sortKey{{Column: "name"}},
+			[]interface{}{"Burundi"},
+			"(((name > ? OR name IS NULL)) OR (name IS NOT DISTINCT FROM ? AND id > ?))",
+			[]interface{}{"Burundi", "Burundi", uint(42)},
+		},
+		{
+			// NULLs sort first in descending order, so none come after a value
+			"descending",
+			[]sortKey{{Column: "population", Desc: true}},
+			[]interface{}{uint64(100)},
+			"((population < ?) OR (population IS NOT DISTINCT FROM ? AND id > ?))",
+			[]interface{}{uint64(100), uint64(100), uint(42)},
+		},
+		{
+			"descending with nulls last",
+			[]sortKey{{Column: "estimated_gdp", Desc: true, Nulls: NullsLast}},
+			[]interface{}{1.5},
+			"(((estimated_gdp < ? OR estimated_gdp IS NULL)) OR (estimated_gdp IS NOT DISTINCT FROM ? AND id > ?))",
+			[]interface{}{1.5, 1.5, uint(42)},
+		},
+		{
+			"null with nulls first",
+			[]sortKey{{Column: "capital", Nulls: NullsFirst}},
+			[]interface{}{nil},
+			"((capital IS NOT NULL) OR (capital IS NOT DISTINCT FROM ? AND id > ?))",
+			[]interface{}{nil, uint(42)},
+		},
+		{
+			"null with nulls last",
+			[]sortKey{{Column: "capital"}},
+			[]interface{}{nil},
+			"((capital IS NOT DISTINCT FROM ? AND id > ?))",
+			[]interface{}{nil, uint(42)},
+		},
+		{
+			"several keys",
+			[]sortKey{{Column: "region", Nulls: NullsFirst}, {Column: "name", Desc: true}},
+			[]interface{}{"Africa", "Burundi"},
+			"((region > ?) OR (region IS NOT DISTINCT FROM ? AND name < ?) OR (region IS NOT DISTINCT FROM ? AND name IS NOT DISTINCT FROM ? AND id > ?))",
+			[]interface{}{"Africa", "Africa", "Burundi", "Africa", "Burundi", uint(42)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := &countryCursor{Values: tt.values, ID: 42}
+			stmt := afterCursor(db.Model(&models.Country{}), tt.keys, "id", cursor).Find(&[]models.Country{}).Statement
+
+			where := "SELECT * FROM `countries` WHERE (" + tt.wantSQL + ") AND `countries`.`deleted_at` IS NULL"
+			if got := stmt.SQL.String(); got != where {
+				t.Errorf("SQL =\n%s\nwant\n%s", got, where)
+			}
+			if !reflect.DeepEqual(stmt.Vars, tt.wantVars) {
+				t.Errorf("vars = %#v, want %#v", stmt.Vars, tt.wantVars)
+			}
+		})
+	}
+}
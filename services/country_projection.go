@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// Related data that can be embedded in country responses
+const (
+	IncludeCurrencies  = "currencies"
+	IncludeOverrides   = "overrides"
+	IncludeRateHistory = "rate_history"
+	IncludeRankings    = "rankings"
+)
+
+// IncludableCountryRelations lists the values accepted by CountryProjection.Include
+var IncludableCountryRelations = []string{IncludeCurrencies, IncludeOverrides, IncludeRateHistory, IncludeRankings}
+
+// SelectableCountryFields lists the fields accepted by CountryProjection.Fields, by JSON name
+var SelectableCountryFields = []string{
+	"id", "name", "capital", "region", "population", "currency_code", "exchange_rate", "estimated_gdp",
+	"gdp_estimator", "gdp_estimator_params", "flag_url", "alt_names", "last_refreshed_at", "stale", "origin",
+}
+
+// AsOfCountryFields lists the selectable fields that snapshots record; the others are left empty in as_of queries
+var AsOfCountryFields = []string{
+	"id", "name", "capital", "region", "population", "currency_code", "exchange_rate", "estimated_gdp",
+	"gdp_estimator", "gdp_estimator_params", "flag_url", "last_refreshed_at",
+}
+
+// rateHistoryLimit is how many of the most recent rates of a country's primary currency rate_history embeds
+const rateHistoryLimit = 30
+
+// CountryProjection selects which fields and related data a country query returns
+type CountryProjection struct {
+	Fields  []string // JSON names of the fields to return; empty for every field
+	Include []string // Related data to embed, from IncludableCountryRelations
+}
+
+// ValidateProjection checks that every field and included relation is known. It returns nil if they are.
+func ValidateProjection(projection CountryProjection) map[string]string {
+	errs := make(map[string]string)
+	for _, field := range projection.Fields {
+		if !slices.Contains(SelectableCountryFields, field) {
+			errs["fields"] = fmt.Sprintf("unknown field %q; allowed fields are %s", field, strings.Join(SelectableCountryFields, ", "))
+			break
+		}
+	}
+	for _, relation := range projection.Include {
+		if !slices.Contains(IncludableCountryRelations, relation) {
+			errs["include"] = fmt.Sprintf("unknown relation %q; allowed values are %s", relation, strings.Join(IncludableCountryRelations, ", "))
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Includes reports whether relation should be embedded. Relations shown by default are embedded
+// unless fields are selected, in which case only explicitly included relations are.
+func (p CountryProjection) Includes(relation string, byDefault bool) bool {
+	return slices.Contains(p.Include, relation) || (byDefault && len(p.Fields) == 0)
+}
+
+// selectColumns restricts query to the selected fields, plus the ID and the extra columns the query needs.
+// With asOf, query is over snapshots and fields they don't record are skipped.
+func (p CountryProjection) selectColumns(query *gorm.DB, asOf bool, extra ...string) *gorm.DB {
+	if len(p.Fields) == 0 {
+		return query
+	}
+	if p.Includes(IncludeRateHistory, false) {
+		extra = append(extra, "currency_code")
+	}
+
+	columns := []string{"id"}
+	if asOf {
+		columns = []string{"country_id AS id"}
+	}
+	seen := map[string]bool{"id": true}
+	for _, column := range append(append([]string{}, p.Fields...), extra...) {
+		if seen[column] || (asOf && !slices.Contains(AsOfCountryFields, column)) {
+			continue
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	return query.Select(strings.Join(columns, ", "))
+}
+
+// loadRateHistory fills in the RateHistory of each country with the most recent rates of its primary
+// currency, newest first. With asOf, only rates fetched by then are included.
+func (s *CountryService) loadRateHistory(countries []models.Country, asOf *time.Time) error {
+	var codes []string
+	for _, country := range countries {
+		if country.CurrencyCode != nil {
+			codes = append(codes, *country.CurrencyCode)
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	ranked := s.db.Model(&models.ExchangeRate{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY currency_code ORDER BY fetched_at DESC, id DESC) AS rate_rank").
+		Where("currency_code IN ?", codes)
+	if asOf != nil {
+		ranked = ranked.Where("fetched_at <= ?", *asOf)
+	}
+	var rates []models.ExchangeRate
+	err := s.db.Table("(?) AS ranked", ranked).
+		Select("id, currency_code, rate, source, refresh_run_id, fetched_at").
+		Where("rate_rank <= ?", rateHistoryLimit).
+		Order("currency_code ASC, fetched_at DESC, id DESC").
+		Find(&rates).Error
+	if err != nil {
+		return fmt.Errorf("failed to load rate history: %w", err)
+	}
+
+	byCode := make(map[string][]models.ExchangeRate)
+	for _, rate := range rates {
+		byCode[rate.CurrencyCode] = append(byCode[rate.CurrencyCode], rate)
+	}
+	for i := range countries {
+		if code := countries[i].CurrencyCode; code != nil {
+			countries[i].RateHistory = byCode[*code]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"stage-2/config"
+	"stage-2/utils"
+)
+
+// ProviderCurrency is a currency used by a ProviderCountry
+type ProviderCurrency struct {
+	Code   string
+	Name   string
+	Symbol string
+}
+
+// ProviderCountry is a country record as returned by a CountryProvider
+type ProviderCountry struct {
+	Name       string
+	Capital    string
+	Region     string
+	Population uint64
+	FlagURL    string
+	AltNames   []string           // Alternate spellings and native names, without Name itself
+	Currencies []ProviderCurrency // In the provider's order; the first is the country's primary currency
+	// GDPPerCapita is in USD; nil if the provider doesn't supply it
+	GDPPerCapita *float64
+}
+
+// CountryProvider fetches the list of countries to cache
+type CountryProvider interface {
+	// Source names the provider in logs and error responses
+	Source() string
+	FetchCountries() ([]ProviderCountry, error)
+}
+
+// NewCountryProvider returns the CountryProvider selected by the configuration
+func NewCountryProvider(cfg config.ProviderConfig, client *utils.HTTPClient) (CountryProvider, error) {
+	switch cfg.CountryProvider {
+	case config.ProviderRestCountries:
+		return &RestCountriesProvider{url: cfg.CountriesURL, client: client}, nil
+	case config.ProviderFile:
+		return &FileCountryProvider{path: cfg.CountriesFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown country provider %q", cfg.CountryProvider)
+	}
+}
+
+// restCountriesResponse mirrors the restcountries.com v2 response format
+type restCountriesResponse []struct {
+	Name       string `json:"name"`
+	Capital    string `json:"capital"`
+	Region     string `json:"region"`
+	Population uint64 `json:"population"`
+	Flag       string `json:"flag"`
+	// AltSpellings and NativeName are copied to ProviderCountry.AltNames
+	AltSpellings []string `json:"altSpellings"`
+	NativeName   string   `json:"nativeName"`
+	// GDPPerCapita is not part of restcountries.com; mirrors and fixture files may add it
+	GDPPerCapita *float64 `json:"gdp_per_capita"`
+	Currencies   []struct {
+		Code   string `json:"code"`
+		Name   string `json:"name"`
+		Symbol string `json:"symbol"`
+	} `json:"currencies"`
+}
+
+// toProviderCountries converts the upstream response into provider records
+func (r restCountriesResponse) toProviderCountries() []ProviderCountry {
+	countries := make([]ProviderCountry, 0, len(r))
+	for _, apiCountry := range r {
+		country := ProviderCountry{
+			Name:         apiCountry.Name,
+			Capital:      apiCountry.Capital,
+			Region:       apiCountry.Region,
+			Population:   apiCountry.Population,
+			FlagURL:      apiCountry.Flag,
+			GDPPerCapita: apiCountry.GDPPerCapita,
+		}
+		seen := map[string]bool{strings.ToLower(apiCountry.Name): true}
+		for _, alt := range append(apiCountry.AltSpellings, apiCountry.NativeName) {
+			if alt = strings.TrimSpace(alt); alt != "" && !seen[strings.ToLower(alt)] {
+				seen[strings.ToLower(alt)] = true
+				country.AltNames = append(country.AltNames, alt)
+			}
+		}
+		for _, currency := range apiCountry.Currencies {
+			if currency.Code != "" {
+				country.Currencies = append(country.Currencies, ProviderCurrency{
+					Code:   currency.Code,
+					Name:   currency.Name,
+					Symbol: currency.Symbol,
+				})
+			}
+		}
+		countries = append(countries, country)
+	}
+	return countries
+}
+
+// RestCountriesProvider fetches countries from restcountries.com or a mirror of its v2 API
+type RestCountriesProvider struct {
+	url    string
+	client *utils.HTTPClient
+}
+
+// Source returns the host the provider fetches from
+func (p *RestCountriesProvider) Source() string {
+	return hostOf(p.url)
+}
+
+// FetchCountries fetches and decodes all countries
+func (p *RestCountriesProvider) FetchCountries() ([]ProviderCountry, error) {
+	var response restCountriesResponse
+	if err := p.client.Get(p.url, &response); err != nil {
+		return nil, err
+	}
+	return response.toProviderCountries(), nil
+}
+
+// FileCountryProvider reads countries from a local JSON file in the restcountries.com v2 format
+type FileCountryProvider struct {
+	path string
+}
+
+// Source returns the file the provider reads from
+func (p *FileCountryProvider) Source() string {
+	return p.path
+}
+
+// FetchCountries reads and decodes all countries from the file
+func (p *FileCountryProvider) FetchCountries() ([]ProviderCountry, error) {
+	var response restCountriesResponse
+	if err := readJSONFile(p.path, &response); err != nil {
+		return nil, err
+	}
+	return response.toProviderCountries(), nil
+}
+
+// readJSONFile decodes the JSON file at path into target
+func readJSONFile(path string, target interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// hostOf returns the host part of rawURL, or rawURL itself if it can't be parsed
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
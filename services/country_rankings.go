@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// RankedMetrics lists the metrics countries are ranked by, largest first
+var RankedMetrics = []string{"population", "estimated_gdp", "gdp_per_capita"}
+
+// metricExpressions maps each ranked metric to its SQL expression over the countries table
+var metricExpressions = map[string]string{
+	"population":     "population",
+	"estimated_gdp":  "estimated_gdp",
+	"gdp_per_capita": "estimated_gdp / NULLIF(population, 0)",
+}
+
+// rankColumns returns the select list computing the global and regional rank and percentile of every metric.
+// Countries without a value are left out of each ranking rather than sorted to the end.
+func rankColumns() string {
+	var columns []string
+	for _, metric := range RankedMetrics {
+		expr := metricExpressions[metric]
+		columns = append(columns,
+			fmt.Sprintf("CASE WHEN %[1]s IS NOT NULL THEN RANK() OVER (ORDER BY %[1]s DESC NULLS LAST) END AS %[2]s_rank", expr, metric),
+			fmt.Sprintf("CASE WHEN %[1]s IS NOT NULL THEN RANK() OVER (PARTITION BY region ORDER BY %[1]s DESC NULLS LAST) END AS %[2]s_region_rank", expr, metric),
+			fmt.Sprintf("CASE WHEN %[1]s IS NOT NULL THEN CUME_DIST() OVER (PARTITION BY %[1]s IS NULL ORDER BY %[1]s) * 100 END AS %[2]s_percentile", expr, metric),
+			fmt.Sprintf("CASE WHEN %[1]s IS NOT NULL THEN CUME_DIST() OVER (PARTITION BY region, %[1]s IS NULL ORDER BY %[1]s) * 100 END AS %[2]s_region_percentile", expr, metric),
+		)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// rankedCountries returns a query over the countries that were current at asOf, or the current countries
+// if asOf is nil, that can be ranked with rankColumns
+func (s *CountryService) rankedCountries(asOf *time.Time) *gorm.DB {
+	if asOf != nil {
+		return s.db.Table("(?) AS versions", s.snapshotsAsOf(*asOf))
+	}
+	return s.db.Model(&models.Country{})
+}
+
+// loadRanks returns the ranks of the given countries by every metric, among all countries current at asOf
+func (s *CountryService) loadRanks(ids []uint, asOf *time.Time) (map[uint]map[string]models.MetricRank, error) {
+	ranks := make(map[uint]map[string]models.MetricRank, len(ids))
+	if len(ids) == 0 {
+		return ranks, nil
+	}
+
+	ranked := s.rankedCountries(asOf).Select("id, " + rankColumns())
+	var rows []map[string]interface{}
+	if err := s.db.Table("(?) AS ranked", ranked).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to rank countries: %w", err)
+	}
+
+	for _, row := range rows {
+		id, ok := row["id"].(int64)
+		if !ok {
+			continue
+		}
+		countryRanks := make(map[string]models.MetricRank, len(RankedMetrics))
+		for _, metric := range RankedMetrics {
+			countryRanks[metric] = models.MetricRank{
+				Global:           rankValue(row[metric+"_rank"]),
+				Region:           rankValue(row[metric+"_region_rank"]),
+				GlobalPercentile: percentileValue(row[metric+"_percentile"]),
+				RegionPercentile: percentileValue(row[metric+"_region_percentile"]),
+			}
+		}
+		ranks[uint(id)] = countryRanks
+	}
+	return ranks, nil
+}
+
+// loadRankings fills in the Rankings of each country
+func (s *CountryService) loadRankings(countries []models.Country, asOf *time.Time) error {
+	ids := make([]uint, len(countries))
+	for i := range countries {
+		ids[i] = countries[i].ID
+	}
+	ranks, err := s.loadRanks(ids, asOf)
+	if err != nil {
+		return err
+	}
+	for i := range countries {
+		countries[i].Rankings = ranks[countries[i].ID]
+	}
+	return nil
+}
+
+// rankValue converts a rank scanned into a map to a nullable integer
+func rankValue(value interface{}) *int64 {
+	if rank, ok := value.(int64); ok {
+		return &rank
+	}
+	return nil
+}
+
+// percentileValue converts a percentile scanned into a map to a nullable number
+func percentileValue(value interface{}) *float64 {
+	if percentile, ok := value.(float64); ok {
+		return &percentile
+	}
+	return nil
+}
+
+// LeaderboardEntry is a country's place in a ranking by one metric
+type LeaderboardEntry struct {
+	Rank       int64   `json:"rank"`
+	Name       string  `json:"name"`
+	Region     *string `json:"region"`
+	Value      float64 `json:"value"`
+	Percentile float64 `json:"percentile"`
+}
+
+// GetLeaderboard ranks the countries current at asOf, or the current countries if asOf is nil, by metric,
+// largest first, leaving out countries without a value. If region is set, only its countries are ranked.
+// At most limit entries are returned.
+func (s *CountryService) GetLeaderboard(metric, region string, asOf *time.Time, limit int) ([]LeaderboardEntry, error) {
+	expr, ok := metricExpressions[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	ranked := s.rankedCountries(asOf).
+		Select(fmt.Sprintf("name, region, %[1]s AS value, RANK() OVER (ORDER BY %[1]s DESC) AS rank, CUME_DIST() OVER (ORDER BY %[1]s) * 100 AS percentile", expr)).
+		Where(expr + " IS NOT NULL")
+	if region != "" {
+		ranked = ranked.Where("LOWER(region) = LOWER(?)", region)
+	}
+
+	entries := []LeaderboardEntry{}
+	err := s.db.Table("(?) AS ranked", ranked).Order("rank ASC, name ASC").Limit(limit).Scan(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank countries by %s: %w", metric, err)
+	}
+	return entries, nil
+}
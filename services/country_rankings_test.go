@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"stage-2/models"
+)
+
+func TestGetLeaderboardAsOf(t *testing.T) {
+	db := openTestDB(t)
+	s := newReplayCountryService(t, db)
+	t.Chdir(t.TempDir())
+	if _, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}}); err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+	refreshed := time.Now().UTC()
+
+	// Ghana overtakes Nigeria after the refresh
+	var ghana models.Country
+	if err := db.Where("name = ?", "Ghana").Take(&ghana).Error; err != nil {
+		t.Fatal(err)
+	}
+	ghana.Population = 300000000
+	if _, err := s.UpdateCountry(AuditContext{Actor: "test"}, "Ghana", &ghana); err != nil {
+		t.Fatalf("UpdateCountry() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		asOf      *time.Time
+		wantNames []string
+		wantValue []float64
+	}{
+		{"current", nil, []string{"Ghana", "Nigeria"}, []float64{300000000, 206139587}},
+		{"as of the refresh", &refreshed, []string{"Nigeria", "Ghana"}, []float64{206139587, 31072940}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := s.GetLeaderboard("population", "africa", tt.asOf, 2)
+			if err != nil {
+				t.Fatalf("GetLeaderboard() error = %v", err)
+			}
+			if len(entries) != len(tt.wantNames) {
+				t.Fatalf("GetLeaderboard() = %+v, want %v", entries, tt.wantNames)
+			}
+			for i, entry := range entries {
+				if entry.Rank != int64(i+1) || entry.Name != tt.wantNames[i] || entry.Value != tt.wantValue[i] {
+					t.Errorf("entry %d = #%d %s (%v), want #%d %s (%v)",
+						i, entry.Rank, entry.Name, entry.Value, i+1, tt.wantNames[i], tt.wantValue[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stage-2/config"
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// reconcileCountries applies policy to the countries that are not in the refreshed feed, identified by keys.
+// It updates the reconciliation counts on run and returns the change log entries for the affected rows,
+// along with the rows as they were before the policy was applied.
+func reconcileCountries(tx *gorm.DB, run *models.RefreshRun, policy string, keys []string, now time.Time) ([]models.CountryChange, []models.Country, error) {
+	run.ReconcilePolicy = policy
+
+	// Countries created through the API were never in the feed
+	query := tx.Where("LOWER(name) NOT IN ? AND origin = ?", keys, models.CountryOriginUpstream)
+	if policy == config.ReconcileKeep {
+		// Rows flagged by an earlier refresh are not affected again
+		query = query.Where("stale = ?", false)
+	}
+	var missing []models.Country
+	if err := query.Find(&missing).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to find countries missing upstream: %w", err)
+	}
+	if len(missing) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make([]uint, len(missing))
+	names := make([]string, len(missing))
+	for i := range missing {
+		ids[i] = missing[i].ID
+		names[i] = nameKey(missing[i].Name)
+	}
+
+	changeType := models.CountryChangeRemoved
+	var fieldChanges map[string]models.FieldChange
+	switch policy {
+	case config.ReconcileKeep:
+		// UpdateColumn leaves last_refreshed_at alone, so it still shows when the row was last seen upstream
+		if err := tx.Model(&models.Country{}).Where("id IN ?", ids).UpdateColumn("stale", true).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to flag stale countries: %w", err)
+		}
+		run.StaleFlagged = len(missing)
+		changeType = models.CountryChangeStale
+		fieldChanges = map[string]models.FieldChange{"stale": {Old: false, New: true}}
+	case config.ReconcileSoftDelete:
+		err := tx.Model(&models.Country{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+			"deleted_at":    now,
+			"delete_reason": models.CountryDeletedMissingUpstream,
+		}).Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to soft-delete countries missing upstream: %w", err)
+		}
+		run.SoftDeleted = len(missing)
+	case config.ReconcileHardDelete:
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Country{}).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to delete countries missing upstream: %w", err)
+		}
+		run.HardDeleted = len(missing)
+	default:
+		return nil, nil, fmt.Errorf("unknown reconcile policy %q", policy)
+	}
+	run.Removed = run.SoftDeleted + run.HardDeleted
+
+	// Removed countries no longer have a current version
+	if run.Removed > 0 {
+		err := tx.Model(&models.CountrySnapshot{}).
+			Where("valid_to IS NULL AND LOWER(name) IN ?", names).
+			Update("valid_to", now).Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to close snapshots of removed countries: %w", err)
+		}
+	}
+
+	changes := make([]models.CountryChange, 0, len(missing))
+	for i := range missing {
+		changes = append(changes, models.CountryChange{
+			RefreshRunID: run.ID,
+			CountryName:  missing[i].Name,
+			ChangeType:   changeType,
+			Changes:      fieldChanges,
+		})
+	}
+	return changes, missing, nil
+}
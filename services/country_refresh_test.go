@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"stage-2/config"
+	"stage-2/models"
+	"stage-2/utils"
+
+	"gorm.io/gorm"
+)
+
+// replayFixturesDir holds upstream responses recorded for the default provider URLs
+const replayFixturesDir = "testdata/fixtures"
+
+// replayProviders returns the default HTTP providers, serving responses from replayFixturesDir
+func replayProviders(tb testing.TB) (CountryProvider, RateProvider) {
+	tb.Helper()
+	// Absolute, so tests can change directory to keep the summary image out of the tree
+	dir, err := filepath.Abs(replayFixturesDir)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	client, err := utils.NewFixtureHTTPClient(utils.HTTPModeReplay, dir)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cfg := config.ProviderConfig{
+		CountryProvider: config.ProviderRestCountries,
+		CountriesURL:    config.DefaultCountriesURL,
+		RateProvider:    config.ProviderERAPI,
+		RatesURL:        config.DefaultRatesURL,
+	}
+	countryProvider, err := NewCountryProvider(cfg, client)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	rateProvider, err := NewRateProvider(cfg, client)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return countryProvider, rateProvider
+}
+
+func TestReplayProviders(t *testing.T) {
+	countryProvider, rateProvider := replayProviders(t)
+
+	countries, err := countryProvider.FetchCountries()
+	if err != nil {
+		t.Fatalf("FetchCountries() error = %v", err)
+	}
+	if len(countries) != 9 {
+		t.Fatalf("FetchCountries() returned %d countries, want 9", len(countries))
+	}
+	byName := make(map[string]ProviderCountry, len(countries))
+	for _, country := range countries {
+		byName[country.Name] = country
+	}
+
+	ivoryCoast := byName["Côte d'Ivoire"]
+	// The native name repeats the name, so it is dropped
+	wantAlt := []string{"CI", "Ivory Coast", "Republic of Côte d'Ivoire", "République de Côte d'Ivoire"}
+	if !slices.Equal(ivoryCoast.AltNames, wantAlt) {
+		t.Errorf("Côte d'Ivoire alt names = %v, want %v", ivoryCoast.AltNames, wantAlt)
+	}
+	var codes []string
+	for _, currency := range byName["Zimbabwe"].Currencies {
+		codes = append(codes, currency.Code)
+	}
+	if want := []string{"ZWL", "USD", "ZAR"}; !slices.Equal(codes, want) {
+		t.Errorf("Zimbabwe currencies = %v, want %v", codes, want)
+	}
+	if currencies := byName["Antarctica"].Currencies; len(currencies) != 0 {
+		t.Errorf("Antarctica currencies = %v, want none", currencies)
+	}
+
+	rates, err := rateProvider.FetchRates()
+	if err != nil {
+		t.Fatalf("FetchRates() error = %v", err)
+	}
+	if rates["EUR"] != 0.861 || rates["USD"] != 1 {
+		t.Errorf("FetchRates() = %v, want EUR 0.861 and USD 1", rates)
+	}
+	if _, ok := rates["BTN"]; ok {
+		t.Errorf("FetchRates() has a BTN rate; the fixtures leave it out to cover missing rates")
+	}
+}
+
+func TestReplayWithoutFixture(t *testing.T) {
+	client, err := utils.NewFixtureHTTPClient(utils.HTTPModeReplay, replayFixturesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewRateProvider(config.ProviderConfig{
+		RateProvider: config.ProviderERAPI,
+		RatesURL:     "https://open.er-api.com/v6/latest/EUR",
+	}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.FetchRates(); !errors.Is(err, utils.ErrNoRecordedResponse) {
+		t.Errorf("FetchRates() error = %v, want ErrNoRecordedResponse", err)
+	}
+}
+
+// newReplayCountryService returns a CountryService that refreshes from the replay fixtures into db,
+// with the fixed GDP estimator so estimates can be checked exactly
+func newReplayCountryService(tb testing.TB, db *gorm.DB) *CountryService {
+	tb.Helper()
+	countryProvider, rateProvider := replayProviders(tb)
+	estimator, err := NewGDPEstimator(config.GDPConfig{Estimator: config.GDPEstimatorFixed, Multiplier: config.DefaultGDPMultiplier})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	refreshConfig := config.RefreshConfig{ReconcilePolicy: config.ReconcileKeep}
+	return NewCountryService(db, countryProvider, rateProvider, estimator, refreshConfig, "test")
+}
+
+func TestRefreshCountriesReplay(t *testing.T) {
+	db := openTestDB(t)
+	s := newReplayCountryService(t, db)
+	// The summary image is written under the working directory
+	t.Chdir(t.TempDir())
+
+	result, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}})
+	if err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+	if result.TotalCountries != 9 || result.Inserted != 9 {
+		t.Errorf("first refresh: total %d, inserted %d; want 9 and 9", result.TotalCountries, result.Inserted)
+	}
+	if _, err := os.Stat(utils.GetSummaryImagePath()); err != nil {
+		t.Errorf("summary image not generated: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		currencyCode *string
+		exchangeRate *float64
+		estimatedGDP *float64
+	}{
+		{"Nigeria", strPtr("NGN"), floatPtr(1600.23), fixedGDP(206139587, 1600.23)},
+		{"France", strPtr("EUR"), floatPtr(0.861), fixedGDP(67391582, 0.861)},
+		{"United States of America", strPtr("USD"), floatPtr(1), fixedGDP(329484123, 1)},
+		{"Zimbabwe", strPtr("ZWL"), floatPtr(26.6), fixedGDP(14862927, 26.6)},
+		{"Bhutan", strPtr("BTN"), nil, nil}, // No BTN rate
+		{"Antarctica", nil, nil, floatPtr(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var country models.Country
+			if err := db.Where("name = ?", tt.name).First(&country).Error; err != nil {
+				t.Fatalf("failed to load %s: %v", tt.name, err)
+			}
+			if !equalPtr(country.CurrencyCode, tt.currencyCode) {
+				t.Errorf("currency_code = %v, want %v", derefString(country.CurrencyCode), derefString(tt.currencyCode))
+			}
+			if !equalPtr(country.ExchangeRate, tt.exchangeRate) {
+				t.Errorf("exchange_rate = %v, want %v", derefFloat(country.ExchangeRate), derefFloat(tt.exchangeRate))
+			}
+			if !equalPtr(country.EstimatedGDP, tt.estimatedGDP) {
+				t.Errorf("estimated_gdp = %v, want %v", derefFloat(country.EstimatedGDP), derefFloat(tt.estimatedGDP))
+			}
+		})
+	}
+
+	// Replaying the same responses changes nothing
+	result, err = s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}})
+	if err != nil {
+		t.Fatalf("second RefreshCountries() error = %v", err)
+	}
+	if result.Unchanged != 9 || result.Inserted != 0 || result.Updated != 0 {
+		t.Errorf("second refresh: inserted %d, updated %d, unchanged %d; want 0, 0 and 9", result.Inserted, result.Updated, result.Unchanged)
+	}
+}
+
+func TestRefreshCountriesAuditsReconciliation(t *testing.T) {
+	db := openTestDB(t)
+	s := newReplayCountryService(t, db)
+	s.refreshConfig.ReconcilePolicy = config.ReconcileHardDelete
+	t.Chdir(t.TempDir())
+
+	// A country an earlier refresh fetched, which the fixtures no longer have
+	gone := models.Country{Name: "Atlantis", Population: 1, Origin: models.CountryOriginUpstream}
+	if err := db.Create(&gone).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}})
+	if err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+	if result.HardDeleted != 1 {
+		t.Fatalf("hard deleted %d countries, want 1", result.HardDeleted)
+	}
+
+	var event models.AuditEvent
+	if err := db.Where("action = ?", models.AuditCountriesRefresh).Take(&event).Error; err != nil {
+		t.Fatalf("failed to load refresh audit event: %v", err)
+	}
+	after, ok := event.After.(map[string]interface{})
+	if !ok {
+		t.Fatalf("after = %v, want an object", event.After)
+	}
+	if after["hard_deleted"] != 1.0 || after["soft_deleted"] != 0.0 || after["reconcile_policy"] != config.ReconcileHardDelete {
+		t.Errorf("after = %v, want 1 hard deleted, 0 soft deleted and policy %s", after, config.ReconcileHardDelete)
+	}
+	if names, _ := after["reconciled_countries"].([]interface{}); len(names) != 1 || names[0] != "Atlantis" {
+		t.Errorf("after.reconciled_countries = %v, want [Atlantis]", after["reconciled_countries"])
+	}
+
+	before, ok := event.Before.(map[string]interface{})
+	if !ok {
+		t.Fatalf("before = %v, want an object", event.Before)
+	}
+	countries, _ := before["reconciled_countries"].([]interface{})
+	if len(countries) != 1 {
+		t.Fatalf("before.reconciled_countries = %v, want the deleted country", before["reconciled_countries"])
+	}
+	if country, _ := countries[0].(map[string]interface{}); country["name"] != "Atlantis" || country["population"] != 1.0 {
+		t.Errorf("before.reconciled_countries[0] = %v, want Atlantis as it was", countries[0])
+	}
+}
+
+// fixedGDP returns the estimate of the fixed estimator with the default multiplier.
+// It is computed at run time, since constant arithmetic is exact and could round differently.
+func fixedGDP(population uint64, rate float64) *float64 {
+	return floatPtr(float64(population) * config.DefaultGDPMultiplier / rate)
+}
+
+// strPtr returns a pointer to v
+func strPtr(v string) *string {
+	return &v
+}
+
+// equalPtr reports whether a and b are both nil or point to equal values
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
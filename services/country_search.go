@@ -0,0 +1,161 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// How a search query matched a country, from strongest to weakest
+const (
+	MatchExact      = "exact"       // The whole field equals the query
+	MatchPrefix     = "prefix"      // The field starts with the query
+	MatchWordPrefix = "word_prefix" // A later word of the field starts with the query
+	MatchFuzzy      = "fuzzy"       // The field is similar to the query by trigrams
+)
+
+// matchTypes maps the match tiers computed by countrySearchSQL to match types
+var matchTypes = []string{MatchExact, MatchPrefix, MatchWordPrefix, MatchFuzzy}
+
+// searchSimilarityThreshold is the minimum trigram similarity of a fuzzy match
+const searchSimilarityThreshold = 0.3
+
+// CountrySearch holds the parameters of a country search
+type CountrySearch struct {
+	Query string
+	Limit int
+	// Typeahead leaves out fuzzy matches, for completing a partially typed name
+	Typeahead bool
+}
+
+// CountrySearchResult is a country matched by a search, along with the field that matched best
+type CountrySearchResult struct {
+	models.Country
+	MatchedField string  `json:"matched_field"` // name, capital or alt_name
+	MatchedValue string  `json:"matched_value"`
+	MatchType    string  `json:"match_type"`
+	Score        float64 `json:"score"` // Trigram similarity between 0 and 1; 1 for exact matches
+}
+
+// searchMatch is the best match of a single country, as computed by countrySearchSQL
+type searchMatch struct {
+	CountryID    uint
+	MatchedField string
+	MatchedValue string
+	Tier         int
+	Score        float64
+}
+
+// countrySearchSQL finds the best-matching field of every candidate country and ranks the countries by it.
+// Names, capitals and alternate names are compared through country_search_key, which ignores case,
+// accents and punctuation. Exact matches rank first, then prefixes, then word prefixes, then fuzzy
+// matches; within each, by similarity, preferring names over capitals over alternate names.
+//
+// Candidates are found through the trigram indexes: a field containing the query, which every exact,
+// prefix and word prefix match does, or similar to it by the % and <% operators. Those operators use
+// the pg_trgm thresholds, which SearchCountries sets to searchSimilarityThreshold. The word similarity
+// of the query to the joined alternate names is at least its similarity to any one of them.
+const countrySearchSQL = `
+SELECT countries.id AS country_id, best.matched_field, best.matched_value, best.tier, best.score
+FROM (SELECT country_search_key(@query) AS key) AS search
+CROSS JOIN countries
+CROSS JOIN LATERAL (
+	SELECT fields.field AS matched_field, fields.value AS matched_value, fields.weight,
+		CASE
+			WHEN fields.key = search.key THEN 0
+			WHEN starts_with(fields.key, search.key) THEN 1
+			WHEN position(' ' || search.key IN ' ' || fields.key) > 0 THEN 2
+			ELSE 3
+		END AS tier,
+		CASE
+			WHEN fields.key = search.key THEN 1
+			ELSE GREATEST(similarity(fields.key, search.key), word_similarity(search.key, fields.key))
+		END AS score
+	FROM (
+		SELECT 'name' AS field, countries.name AS value, country_search_key(countries.name) AS key, 0 AS weight
+		UNION ALL
+		SELECT 'capital', countries.capital, country_search_key(countries.capital), 1
+		WHERE countries.capital IS NOT NULL AND countries.capital <> ''
+		UNION ALL
+		SELECT 'alt_name', alt, country_search_key(alt), 2
+		FROM jsonb_array_elements_text(COALESCE(countries.alt_names, '[]'::jsonb)) AS alt
+	) AS fields
+	ORDER BY tier, score DESC, weight
+	LIMIT 1
+) AS best
+WHERE countries.deleted_at IS NULL AND search.key <> ''
+	AND (
+		country_search_key(countries.name) LIKE '%' || search.key || '%'
+		OR country_search_key(countries.capital) LIKE '%' || search.key || '%'
+		OR country_alt_search_key(countries.alt_names) LIKE '%' || search.key || '%'
+		OR (@fuzzy AND (
+			country_search_key(countries.name) % search.key
+			OR search.key <% country_search_key(countries.name)
+			OR country_search_key(countries.capital) % search.key
+			OR search.key <% country_search_key(countries.capital)
+			OR search.key <% country_alt_search_key(countries.alt_names)
+		))
+	)
+	AND (best.tier < 3 OR (@fuzzy AND best.score >= @threshold))
+ORDER BY best.tier, best.score DESC, best.weight, countries.name
+LIMIT @limit`
+
+// SearchCountries finds countries whose name, capital or an alternate name matches the query,
+// ignoring case and accents, ranked from the best match down
+func (s *CountryService) SearchCountries(search CountrySearch) ([]CountrySearchResult, error) {
+	var matches []searchMatch
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// The thresholds of the % and <% operators, for this transaction only
+		threshold := strconv.FormatFloat(searchSimilarityThreshold, 'f', -1, 64)
+		err := tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', ?, true), set_config('pg_trgm.word_similarity_threshold', ?, true)",
+			threshold, threshold).Error
+		if err != nil {
+			return err
+		}
+		return tx.Raw(countrySearchSQL, map[string]interface{}{
+			"query":     search.Query,
+			"fuzzy":     !search.Typeahead,
+			"threshold": searchSimilarityThreshold,
+			"limit":     search.Limit,
+		}).Scan(&matches).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search countries: %w", err)
+	}
+
+	results := []CountrySearchResult{}
+	if len(matches) == 0 {
+		return results, nil
+	}
+
+	ids := make([]uint, len(matches))
+	for i, match := range matches {
+		ids[i] = match.CountryID
+	}
+	var countries []models.Country
+	if err := s.db.Where("id IN ?", ids).Find(&countries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch matched countries: %w", err)
+	}
+	byID := make(map[uint]models.Country, len(countries))
+	for _, country := range countries {
+		byID[country.ID] = country
+	}
+
+	for _, match := range matches {
+		country, ok := byID[match.CountryID]
+		if !ok {
+			continue // Deleted since the search ran
+		}
+		results = append(results, CountrySearchResult{
+			Country:      country,
+			MatchedField: match.MatchedField,
+			MatchedValue: match.MatchedValue,
+			MatchType:    matchTypes[match.Tier],
+			Score:        match.Score,
+		})
+	}
+	return results, nil
+}
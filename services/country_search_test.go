@@ -0,0 +1,97 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// newSearchTestService returns a CountryService over the countries of the replay fixtures
+func newSearchTestService(t *testing.T) (*CountryService, *gorm.DB) {
+	t.Helper()
+	db := openTestDB(t)
+	s := newReplayCountryService(t, db)
+	t.Chdir(t.TempDir())
+	if _, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}}); err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+	return s, db
+}
+
+func TestSearchCountries(t *testing.T) {
+	s, _ := newSearchTestService(t)
+
+	tests := []struct {
+		name      string
+		search    CountrySearch
+		wantName  string // The best match; empty if nothing matches
+		wantField string
+		wantType  string
+	}{
+		{"exact name", CountrySearch{Query: "Nigeria"}, "Nigeria", "name", MatchExact},
+		{"accents and punctuation", CountrySearch{Query: "cote d'ivoire"}, "Côte d'Ivoire", "name", MatchExact},
+		{"name prefix", CountrySearch{Query: "zimb"}, "Zimbabwe", "name", MatchPrefix},
+		{"capital prefix", CountrySearch{Query: "yamous"}, "Côte d'Ivoire", "capital", MatchPrefix},
+		{"alternate name", CountrySearch{Query: "ivory"}, "Côte d'Ivoire", "alt_name", MatchPrefix},
+		{"alternate name word", CountrySearch{Query: "coast"}, "Côte d'Ivoire", "alt_name", MatchWordPrefix},
+		{"typo", CountrySearch{Query: "nigria"}, "Nigeria", "name", MatchFuzzy},
+		{"typo in an alternate name", CountrySearch{Query: "ivroy coast"}, "Côte d'Ivoire", "alt_name", MatchFuzzy},
+		{"typeahead leaves out typos", CountrySearch{Query: "nigria", Typeahead: true}, "", "", ""},
+		{"no match", CountrySearch{Query: "xyzzy"}, "", "", ""},
+		{"only punctuation", CountrySearch{Query: "'-"}, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.search.Limit = 10
+			results, err := s.SearchCountries(tt.search)
+			if err != nil {
+				t.Fatalf("SearchCountries() error = %v", err)
+			}
+			if tt.wantName == "" {
+				if len(results) > 0 {
+					t.Errorf("SearchCountries() found %s, want nothing", results[0].Name)
+				}
+				return
+			}
+			if len(results) == 0 {
+				t.Fatalf("SearchCountries() found nothing, want %s", tt.wantName)
+			}
+			best := results[0]
+			if best.Name != tt.wantName || best.MatchedField != tt.wantField || best.MatchType != tt.wantType {
+				t.Errorf("best match = %s by %s (%s), want %s by %s (%s)",
+					best.Name, best.MatchedField, best.MatchType, tt.wantName, tt.wantField, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestSearchCountriesUsesIndexes(t *testing.T) {
+	_, db := newSearchTestService(t)
+
+	// With sequential scans priced out, the plan scans the trigram indexes if the query can use them
+	var plan []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL enable_seqscan = off").Error; err != nil {
+			return err
+		}
+		return tx.Raw("EXPLAIN "+countrySearchSQL, map[string]interface{}{
+			"query":     "ivory",
+			"fuzzy":     true,
+			"threshold": searchSimilarityThreshold,
+			"limit":     10,
+		}).Scan(&plan).Error
+	})
+	if err != nil {
+		t.Fatalf("failed to explain the search query: %v", err)
+	}
+
+	explained := strings.Join(plan, "\n")
+	for _, index := range []string{"idx_countries_name_trgm", "idx_countries_capital_trgm", "idx_countries_alt_names_trgm"} {
+		if !strings.Contains(explained, index) {
+			t.Errorf("search plan doesn't use %s:\n%s", index, explained)
+		}
+	}
+}
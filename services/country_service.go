@@ -4,9 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
+	"sort"
 	"time"
 
+	"stage-2/config"
 	"stage-2/models"
 	"stage-2/utils"
 
@@ -15,125 +16,282 @@ import (
 
 // CountryService handles business logic related to countries
 type CountryService struct {
-	db         *gorm.DB
-	httpClient *utils.HTTPClient
+	db              *gorm.DB
+	countryProvider CountryProvider
+	rateProvider    RateProvider
+	gdpEstimator    GDPEstimator
+	refreshConfig   config.RefreshConfig
+	instanceID      string
 }
 
-// NewCountryService creates a new CountryService
-func NewCountryService(db *gorm.DB) *CountryService {
+// NewCountryService creates a new CountryService; instanceID is recorded on the status of refreshes it runs
+func NewCountryService(db *gorm.DB, cp CountryProvider, rp RateProvider, est GDPEstimator, rc config.RefreshConfig, instanceID string) *CountryService {
 	return &CountryService{
-		db:         db,
-		httpClient: utils.NewHTTPClient(),
+		db:              db,
+		countryProvider: cp,
+		rateProvider:    rp,
+		gdpEstimator:    est,
+		refreshConfig:   rc,
+		instanceID:      instanceID,
 	}
 }
 
-// RefreshCountries fetches data from external APIs, processes it, and updates the database
-func (s *CountryService) RefreshCountries() (int, time.Time, error) {
-	var (
-		countriesAPIResponse []struct {
-			Name       string `json:"name"`
-			Capital    string `json:"capital"`
-			Region     string `json:"region"`
-			Population uint64 `json:"population"`
-			Flag       string `json:"flag"`
-			Currencies []struct {
-				Code string `json:"code"`
-			} `json:"currencies"`
-		}
-		exchangeRatesAPIResponse struct {
-			Rates map[string]float64 `json:"rates"`
+// RefreshProgressFunc receives phase and row count updates while a refresh is running
+type RefreshProgressFunc func(phase string, processed, total int)
+
+// RefreshOptions configures a single refresh run
+type RefreshOptions struct {
+	Trigger  string              // models.RefreshTriggerManual or models.RefreshTriggerScheduled
+	JobID    *uint               // The refresh job that started the run, if any
+	Progress RefreshProgressFunc // Optional progress callback
+	Audit    AuditContext        // Who the refresh is recorded against in the audit log
+}
+
+// RefreshResult summarizes a successful refresh run
+type RefreshResult struct {
+	RunID          uint
+	TotalCountries int
+	Inserted       int
+	Updated        int
+	Unchanged      int
+	Removed        int
+	StaleFlagged   int
+	SoftDeleted    int
+	HardDeleted    int
+	RefreshedAt    time.Time
+}
+
+// RefreshCountries fetches data from external APIs, processes it, and updates the database.
+// Every call is recorded as a models.RefreshRun, whether it succeeds or fails.
+func (s *CountryService) RefreshCountries(opts RefreshOptions) (*RefreshResult, error) {
+	run := models.RefreshRun{
+		RefreshJobID: opts.JobID,
+		Trigger:      opts.Trigger,
+		Source:       s.countryProvider.Source() + ", " + s.rateProvider.Source(),
+		InstanceID:   s.instanceID,
+		Outcome:      models.RefreshRunRunning,
+		StartedAt:    time.Now().UTC(),
+	}
+	if err := s.db.Create(&run).Error; err != nil {
+		return nil, fmt.Errorf("failed to record refresh run: %w", err)
+	}
+
+	result, err := s.refresh(&run, opts)
+
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	if err != nil {
+		errMsg := err.Error()
+		run.Outcome = models.RefreshRunFailed
+		run.Error = &errMsg
+		// Counts from a rolled back transaction never took effect
+		run.Inserted, run.Updated, run.Unchanged, run.Removed = 0, 0, 0, 0
+		run.StaleFlagged, run.SoftDeleted, run.HardDeleted = 0, 0, 0
+	} else {
+		run.Outcome = models.RefreshRunSucceeded
+	}
+	if saveErr := s.db.Save(&run).Error; saveErr != nil {
+		log.Printf("Warning: Failed to record outcome of refresh run %d: %v", run.ID, saveErr)
+	}
+
+	return result, err
+}
+
+// refresh runs the refresh pipeline, filling in the counts on run and logging per-country changes against it
+func (s *CountryService) refresh(run *models.RefreshRun, opts RefreshOptions) (*RefreshResult, error) {
+	progress := opts.Progress
+	report := func(phase string, processed, total int) {
+		if progress != nil {
+			progress(phase, processed, total)
 		}
-	)
+	}
 
 	// Fetch countries data
-	countriesURL := "https://restcountries.com/v2/all?fields=name,capital,region,population,flag,currencies"
-	if err := s.httpClient.Get(countriesURL, &countriesAPIResponse); err != nil {
-		return 0, time.Time{}, &utils.ExternalAPIError{Source: "restcountries.com", Err: err}
+	report(models.RefreshPhaseFetchingCountries, 0, 0)
+	providerCountries, err := s.countryProvider.FetchCountries()
+	if err != nil {
+		return nil, &utils.ExternalAPIError{Source: s.countryProvider.Source(), Err: err}
 	}
-	log.Printf("Fetched %d countries from external API", len(countriesAPIResponse))
+	log.Printf("Fetched %d countries from %s", len(providerCountries), s.countryProvider.Source())
 
 	// Fetch exchange rates
-	exchangeRatesURL := "https://open.er-api.com/v6/latest/USD"
-	if err := s.httpClient.Get(exchangeRatesURL, &exchangeRatesAPIResponse); err != nil {
-		return 0, time.Time{}, &utils.ExternalAPIError{Source: "open.er-api.com", Err: err}
+	report(models.RefreshPhaseFetchingRates, 0, len(providerCountries))
+	rates, err := s.rateProvider.FetchRates()
+	if err != nil {
+		return nil, &utils.ExternalAPIError{Source: s.rateProvider.Source(), Err: err}
+	}
+	log.Printf("Fetched %d exchange rates from %s", len(rates), s.rateProvider.Source())
+
+	overrides, err := s.loadOverrides()
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := s.loadUnrestorableKeys()
+	if err != nil {
+		return nil, err
 	}
-	log.Printf("Fetched %d exchange rates from external API", len(exchangeRatesAPIResponse.Rates))
 
 	now := time.Now().UTC()
 	var processedCountries []models.Country
+	// currencyCodes[i] lists every currency code of processedCountries[i]
+	var currencyCodes [][]string
+	currencies := make(map[string]*models.Currency)
 
 	// Process countries and calculate estimated GDP
-	for _, apiCountry := range countriesAPIResponse {
+	seenNames := make(map[string]bool, len(providerCountries))
+	for _, providerCountry := range providerCountries {
+		// A batched upsert can't touch the same row twice
+		key := nameKey(providerCountry.Name)
+		if seenNames[key] {
+			log.Printf("Warning: Skipping duplicate country %s from %s", providerCountry.Name, s.countryProvider.Source())
+			continue
+		}
+		seenNames[key] = true
+		if deleted[key] {
+			log.Printf("Skipping country %s, which was deleted through the API", providerCountry.Name)
+			continue
+		}
+
 		country := models.Country{
-			Name:            apiCountry.Name,
-			Capital:         &apiCountry.Capital,
-			Region:          &apiCountry.Region,
-			Population:      apiCountry.Population,
-			FlagURL:         &apiCountry.Flag,
+			Name:            providerCountry.Name,
+			Capital:         &providerCountry.Capital,
+			Region:          &providerCountry.Region,
+			Population:      providerCountry.Population,
+			FlagURL:         &providerCountry.FlagURL,
+			AltNames:        providerCountry.AltNames,
 			LastRefreshedAt: now,
+			Origin:          models.CountryOriginUpstream,
 		}
 
+		// Manual overrides win over upstream values. Currency and rate overrides are applied
+		// before the GDP estimate so it is computed from the corrected inputs.
+		countryOverrides := overrides[key]
+		applyOverrides(&country, countryOverrides, "capital", "region", "population", "flag_url")
+
+		// Collect every currency the country uses
+		var codes []string
+		for _, providerCurrency := range providerCountry.Currencies {
+			codes = append(codes, providerCurrency.Code)
+			if _, seen := currencies[providerCurrency.Code]; !seen {
+				currencies[providerCurrency.Code] = newCurrency(providerCurrency, rates, now)
+			}
+		}
+		currencyCodes = append(currencyCodes, codes)
+
 		// Handle currency code
-		if len(apiCountry.Currencies) > 0 {
-			currencyCode := apiCountry.Currencies[0].Code
+		if len(providerCountry.Currencies) > 0 {
+			currencyCode := providerCountry.Currencies[0].Code
 			country.CurrencyCode = &currencyCode
+		}
+		applyOverrides(&country, countryOverrides, "currency_code")
+		if country.CurrencyCode != nil {
+			currencyCode := *country.CurrencyCode
 
 			// Match exchange rate
-			if rate, ok := exchangeRatesAPIResponse.Rates[currencyCode]; ok {
+			if rate, ok := rates[currencyCode]; ok {
 				country.ExchangeRate = &rate
-				// Compute estimated_gdp = population × random(1000–2000) ÷ exchange_rate
-				randomMultiplier := float64(rand.Intn(1001) + 1000) // Random number between 1000 and 2000
-				estimatedGDP := float64(country.Population) * randomMultiplier / rate
-				country.EstimatedGDP = &estimatedGDP
 			} else {
-				log.Printf("Exchange rate for currency code %s not found for country %s. Setting exchange_rate and estimated_gdp to null.", currencyCode, country.Name)
-				// Set to null as per requirements
-				country.ExchangeRate = nil
-				country.EstimatedGDP = nil
+				log.Printf("Exchange rate for currency code %s not found for country %s. Setting exchange_rate to null.", currencyCode, country.Name)
 			}
 		} else {
-			log.Printf("No currency found for country %s. Setting currency_code, exchange_rate, and estimated_gdp to null/0.", country.Name)
-			// Set to null/0 as per requirements
-			country.CurrencyCode = nil
-			country.ExchangeRate = nil
-			estimatedGDP := 0.0 // Set to 0.0 for consistency if no currency
-			country.EstimatedGDP = &estimatedGDP
+			log.Printf("No currency found for country %s. Setting currency_code and exchange_rate to null.", country.Name)
 		}
+		applyOverrides(&country, countryOverrides, "exchange_rate")
+
+		// Compute estimated_gdp with the configured estimator
+		s.estimateGDP(&country, providerCountry.GDPPerCapita, now)
+		applyOverrides(&country, countryOverrides, "estimated_gdp")
 
 		processedCountries = append(processedCountries, country)
 	}
 
 	// Use a transaction for atomic updates/inserts
+	report(models.RefreshPhaseSaving, 0, len(processedCountries))
 	tx := s.db.Begin()
 	if tx.Error != nil {
-		return 0, time.Time{}, fmt.Errorf("failed to begin transaction: %w", tx.Error)
+		return nil, fmt.Errorf("failed to begin transaction: %w", tx.Error)
 	}
 
-	for _, country := range processedCountries {
-		var existingCountry models.Country
-		// Case-insensitive comparison for name
-		res := tx.Where("LOWER(name) = LOWER(?)", country.Name).First(&existingCountry)
+	if err := upsertCurrencies(tx, currencies); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-		if res.Error != nil {
-			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-				// Insert new record
-				if err := tx.Create(&country).Error; err != nil {
-					tx.Rollback()
-					return 0, time.Time{}, fmt.Errorf("failed to insert country %s: %w", country.Name, err)
-				}
-			} else {
-				// Other database error
-				tx.Rollback()
-				return 0, time.Time{}, fmt.Errorf("database error checking country %s: %w", country.Name, res.Error)
-			}
-		} else {
-			// Update existing record
-			country.ID = existingCountry.ID // Preserve ID for update
-			if err := tx.Save(&country).Error; err != nil {
-				tx.Rollback()
-				return 0, time.Time{}, fmt.Errorf("failed to update country %s: %w", country.Name, err)
-			}
+	changes, err := upsertCountries(tx, run, processedCountries, currencyCodes, currencies, now, progress)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Reconcile countries that are no longer upstream. An empty feed is more likely
+	// an upstream fault than every country disappearing, so it is not reconciled.
+	var reconciled []models.Country
+	if len(processedCountries) > 0 {
+		refreshedKeys := make([]string, len(processedCountries))
+		for i := range processedCountries {
+			refreshedKeys[i] = nameKey(processedCountries[i].Name)
+		}
+		var removed []models.CountryChange
+		removed, reconciled, err = reconcileCountries(tx, run, s.refreshConfig.ReconcilePolicy, refreshedKeys, now)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
 		}
+		changes = append(changes, removed...)
+	}
+
+	// Keep every fetched rate, not just the ones matched to a country
+	rateHistory := make([]models.ExchangeRate, 0, len(rates))
+	for _, code := range sortedKeys(rates) {
+		rateHistory = append(rateHistory, models.ExchangeRate{
+			CurrencyCode: code,
+			Rate:         rates[code],
+			Source:       s.rateProvider.Source(),
+			RefreshRunID: &run.ID,
+			FetchedAt:    now,
+		})
+	}
+	if len(rateHistory) > 0 {
+		if err := tx.CreateInBatches(&rateHistory, 200).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to record exchange rate history: %w", err)
+		}
+	}
+
+	// Record the per-country change log for this run
+	if len(changes) > 0 {
+		if err := tx.CreateInBatches(&changes, 100).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to record country changes: %w", err)
+		}
+	}
+
+	// Reconciled countries are recorded as they were, since soft and hard deletions leave no row behind to show it
+	reconciledNames := make([]string, len(reconciled))
+	for i := range reconciled {
+		reconciledNames[i] = reconciled[i].Name
+	}
+	var before interface{}
+	if len(reconciled) > 0 {
+		before = map[string]interface{}{"reconciled_countries": reconciled}
+	}
+	err = recordAudit(tx, opts.Audit, models.AuditCountriesRefresh, nil, before, map[string]interface{}{
+		"refresh_run_id":       run.ID,
+		"trigger":              run.Trigger,
+		"inserted":             run.Inserted,
+		"updated":              run.Updated,
+		"unchanged":            run.Unchanged,
+		"removed":              run.Removed,
+		"reconcile_policy":     run.ReconcilePolicy,
+		"stale_flagged":        run.StaleFlagged,
+		"soft_deleted":         run.SoftDeleted,
+		"hard_deleted":         run.HardDeleted,
+		"reconciled_countries": reconciledNames,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	// Update global status
@@ -142,34 +300,36 @@ func (s *CountryService) RefreshCountries() (int, time.Time, error) {
 	res := tx.First(&status, 1)
 	if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
 		tx.Rollback()
-		return 0, time.Time{}, fmt.Errorf("failed to retrieve status: %w", res.Error)
+		return nil, fmt.Errorf("failed to retrieve status: %w", res.Error)
 	}
 
 	status.ID = 1 // Ensure the ID is always 1 for this singleton status record
 	status.TotalCountries = len(processedCountries)
 	status.LastRefreshedAt = now
+	status.LastRefreshedBy = s.instanceID
 
 	if res.Error != nil && errors.Is(res.Error, gorm.ErrRecordNotFound) {
 		// Create if not found
 		if err := tx.Create(&status).Error; err != nil {
 			tx.Rollback()
-			return 0, time.Time{}, fmt.Errorf("failed to create status record: %w", err)
+			return nil, fmt.Errorf("failed to create status record: %w", err)
 		}
 	} else {
-		// Update if found
-		if err := tx.Save(&status).Error; err != nil {
+		// Update if found; the scheduler owns last_scheduled_run_at and may have claimed a tick meanwhile
+		if err := tx.Omit("last_scheduled_run_at").Save(&status).Error; err != nil {
 			tx.Rollback()
-			return 0, time.Time{}, fmt.Errorf("failed to update status record: %w", err)
+			return nil, fmt.Errorf("failed to update status record: %w", err)
 		}
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		return 0, time.Time{}, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	log.Printf("Successfully refreshed %d countries in the database. Last refreshed at: %s", len(processedCountries), now.String())
 
 	// Image Generation
+	report(models.RefreshPhaseGeneratingImage, len(processedCountries), len(processedCountries))
 	// Get top 5 countries by estimated GDP for image
 	var allCountriesInDB []models.Country
 	if err := s.db.Order("estimated_gdp DESC").Limit(5).Find(&allCountriesInDB).Error; err != nil {
@@ -181,68 +341,275 @@ func (s *CountryService) RefreshCountries() (int, time.Time, error) {
 		log.Printf("Warning: Failed to generate summary image: %v", err)
 	}
 
-	return len(processedCountries), now, nil
+	report(models.RefreshPhaseDone, len(processedCountries), len(processedCountries))
+	return &RefreshResult{
+		RunID:          run.ID,
+		TotalCountries: len(processedCountries),
+		Inserted:       run.Inserted,
+		Updated:        run.Updated,
+		Unchanged:      run.Unchanged,
+		Removed:        run.Removed,
+		StaleFlagged:   run.StaleFlagged,
+		SoftDeleted:    run.SoftDeleted,
+		HardDeleted:    run.HardDeleted,
+		RefreshedAt:    now,
+	}, nil
 }
 
-// GetCountries fetches all countries from the database with optional filters and sorting
-func (s *CountryService) GetCountries(region, currency, sort string) ([]models.Country, error) {
-	var countries []models.Country
-	query := s.db.Model(&models.Country{})
+// estimateGDP sets the estimated GDP of country with the configured estimator. Without enough data
+// there is no estimate, except that countries without a currency get 0.
+func (s *CountryService) estimateGDP(country *models.Country, gdpPerCapita *float64, now time.Time) {
+	estimate := s.gdpEstimator.Estimate(GDPInput{
+		Name:         country.Name,
+		Population:   country.Population,
+		ExchangeRate: country.ExchangeRate,
+		GDPPerCapita: gdpPerCapita,
+		RefreshedAt:  now,
+	})
+	country.EstimatedGDP, country.GDPEstimator, country.GDPEstimatorParams = nil, nil, nil
+	if estimate.Value != nil {
+		country.EstimatedGDP = estimate.Value
+		country.GDPEstimator = &estimate.Estimator
+		country.GDPEstimatorParams = estimate.Params
+	} else if country.CurrencyCode == nil {
+		estimatedGDP := 0.0 // Set to 0.0 for consistency if no currency
+		country.EstimatedGDP = &estimatedGDP
+	}
+}
 
-	if region != "" {
-		query = query.Where("LOWER(region) = LOWER(?)", region)
+// diffCountries returns the refreshed fields whose values differ between before and after
+func diffCountries(before, after *models.Country) map[string]models.FieldChange {
+	diff := make(map[string]models.FieldChange)
+	addIfChanged := func(field string, old, new interface{}) {
+		if old != new {
+			diff[field] = models.FieldChange{Old: old, New: new}
+		}
+	}
+
+	addIfChanged("name", before.Name, after.Name)
+	addIfChanged("capital", derefString(before.Capital), derefString(after.Capital))
+	addIfChanged("region", derefString(before.Region), derefString(after.Region))
+	addIfChanged("population", before.Population, after.Population)
+	addIfChanged("currency_code", derefString(before.CurrencyCode), derefString(after.CurrencyCode))
+	addIfChanged("exchange_rate", derefFloat(before.ExchangeRate), derefFloat(after.ExchangeRate))
+	addIfChanged("estimated_gdp", derefFloat(before.EstimatedGDP), derefFloat(after.EstimatedGDP))
+	addIfChanged("gdp_estimator", derefString(before.GDPEstimator), derefString(after.GDPEstimator))
+	addIfChanged("flag_url", derefString(before.FlagURL), derefString(after.FlagURL))
+	addIfChanged("stale", before.Stale, after.Stale)
+	return diff
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	if currency != "" {
-		query = query.Where("LOWER(currency_code) = LOWER(?)", currency)
+	sort.Strings(keys)
+	return keys
+}
+
+// derefString returns the value of p, or nil if p is nil, so it compares by value
+func derefString(p *string) interface{} {
+	if p == nil {
+		return nil
 	}
+	return *p
+}
 
-	// Default sort order
-	orderBy := "name ASC"
-	switch sort {
-	case "gdp_desc":
-		orderBy = "estimated_gdp DESC"
-	case "gdp_asc":
-		orderBy = "estimated_gdp ASC"
-	case "name_desc":
-		orderBy = "name DESC"
-	case "name_asc":
-		orderBy = "name ASC"
-	case "population_desc":
-		orderBy = "population DESC"
-	case "population_asc":
-		orderBy = "population ASC"
+// derefFloat returns the value of p, or nil if p is nil, so it compares by value
+func derefFloat(p *float64) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// GetCountries fetches a page of countries from the database with optional filters and sorting.
+// Overrides are embedded unless projection selects fields without including them.
+func (s *CountryService) GetCountries(filter CountryFilter, page Pagination, projection CountryProjection) (*CountryPage, error) {
+	query, idColumn := s.filteredCountries(filter)
+
+	// A new session lets the filtered query be reused for the count and the page
+	query = query.Session(&gorm.Session{})
+	result := &CountryPage{Countries: []models.Country{}}
+	if err := query.Count(&result.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count countries: %w", err)
+	}
+
+	keys, err := sortCountries(filter)
+	if err != nil {
+		return nil, err
+	}
+	// The ID breaks ties, so pages never overlap or skip rows
+	query = query.Order(orderClause(keys, idColumn))
+	// Sort columns are always selected, since cursors are built from them
+	sortColumns := make([]string, len(keys))
+	for i, key := range keys {
+		sortColumns[i] = key.Column
 	}
-	query = query.Order(orderBy)
+	query = projection.selectColumns(query, filter.AsOf != nil, sortColumns...)
 
-	if err := query.Find(&countries).Error; err != nil {
+	if page.PerPage > 0 {
+		if page.Cursor != "" {
+			cursor, err := decodeCursor(keys, page.Cursor)
+			if err != nil {
+				return nil, err
+			}
+			query = afterCursor(query, keys, idColumn, cursor)
+		} else if page.Page > 1 {
+			query = query.Offset((page.Page - 1) * page.PerPage)
+		}
+		// Fetch one extra row to find out whether there is a next page
+		query = query.Limit(page.PerPage + 1)
+	}
+
+	// Snapshots don't record currency links or overrides, so they are only shown for current data
+	if filter.AsOf == nil {
+		query = preloadRelations(query, projection, false)
+	}
+	if err := query.Find(&result.Countries).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch countries: %w", err)
 	}
-	return countries, nil
+
+	if page.PerPage > 0 && len(result.Countries) > page.PerPage {
+		result.Countries = result.Countries[:page.PerPage]
+		result.NextCursor = encodeCursor(keys, &result.Countries[page.PerPage-1])
+	}
+	if projection.Includes(IncludeRateHistory, false) {
+		if err := s.loadRateHistory(result.Countries, filter.AsOf); err != nil {
+			return nil, err
+		}
+	}
+	if projection.Includes(IncludeRankings, false) {
+		if err := s.loadRankings(result.Countries, filter.AsOf); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-// GetCountryByName fetches a single country by its name
-func (s *CountryService) GetCountryByName(name string) (*models.Country, error) {
+// StreamCountries calls fn with every country matching filter, in sort order, reading them one row at a time
+// instead of loading them all. Only the fields selected by projection are read; relations are never loaded.
+// It stops at the first error returned by fn.
+func (s *CountryService) StreamCountries(filter CountryFilter, projection CountryProjection, fn func(*models.Country) error) error {
+	query, idColumn := s.filteredCountries(filter)
+	keys, err := sortCountries(filter)
+	if err != nil {
+		return err
+	}
+	query = projection.selectColumns(query.Order(orderClause(keys, idColumn)), filter.AsOf != nil)
+
+	rows, err := query.Rows()
+	if err != nil {
+		return fmt.Errorf("failed to fetch countries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var country models.Country
+		if err := s.db.ScanRows(rows, &country); err != nil {
+			return fmt.Errorf("failed to read country: %w", err)
+		}
+		if err := fn(&country); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to fetch countries: %w", err)
+	}
+	return nil
+}
+
+// filteredCountries returns a query over the countries matching filter, or over their snapshots with AsOf,
+// along with the column that identifies a country in it
+func (s *CountryService) filteredCountries(filter CountryFilter) (*gorm.DB, string) {
+	query := s.db.Model(&models.Country{})
+	idColumn := "id"
+	if filter.AsOf != nil {
+		query = s.snapshotsAsOf(*filter.AsOf)
+		idColumn = "country_id"
+	}
+	return applyCountryFilter(query, filter), idColumn
+}
+
+// sortCountries returns the sort keys of filter
+func sortCountries(filter CountryFilter) ([]sortKey, error) {
+	keys, errs := parseSort(filter.Sort, filter.Nulls)
+	if errs != nil {
+		return nil, fmt.Errorf("invalid sort %q: %v", filter.Sort, errs)
+	}
+	return keys, nil
+}
+
+// GetCountryByName fetches a single country by its name, as it stood at asOf if asOf is set.
+// Currencies and overrides are embedded unless projection selects fields without including them.
+func (s *CountryService) GetCountryByName(name string, asOf *time.Time, projection CountryProjection) (*models.Country, error) {
 	var country models.Country
+	// Currencies and overrides only belong to the current version of a country
+	query := preloadRelations(s.db.Model(&models.Country{}), projection, true)
+	if asOf != nil {
+		query = s.snapshotsAsOf(*asOf)
+	}
+	query = projection.selectColumns(query, asOf != nil)
 	// Case-insensitive search
-	if err := s.db.Where("LOWER(name) = LOWER(?)", name).First(&country).Error; err != nil {
+	if err := query.Where("LOWER(name) = LOWER(?)", name).Take(&country).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil // Country not found
 		}
 		return nil, fmt.Errorf("failed to fetch country by name %s: %w", name, err)
 	}
+	countries := []models.Country{country}
+	if projection.Includes(IncludeRateHistory, false) {
+		if err := s.loadRateHistory(countries, asOf); err != nil {
+			return nil, err
+		}
+	}
+	if projection.Includes(IncludeRankings, false) {
+		if err := s.loadRankings(countries, asOf); err != nil {
+			return nil, err
+		}
+	}
+	country = countries[0]
 	return &country, nil
 }
 
-// DeleteCountry deletes a country record by its name
-func (s *CountryService) DeleteCountry(name string) error {
-	result := s.db.Where("LOWER(name) = LOWER(?)", name).Delete(&models.Country{})
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete country %s: %w", name, result.Error)
+// preloadRelations preloads the currencies and overrides the projection embeds.
+// withCurrencies is whether currencies are embedded by default.
+func preloadRelations(query *gorm.DB, projection CountryProjection, withCurrencies bool) *gorm.DB {
+	if projection.Includes(IncludeCurrencies, withCurrencies) {
+		query = query.Preload("Currencies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("code ASC")
+		})
 	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound // Indicate that no record was found to delete
+	if projection.Includes(IncludeOverrides, true) {
+		query = query.Preload("Overrides")
 	}
-	return nil
+	return query
+}
+
+// DeleteCountry soft-deletes a country by its name; it stays in the trash until it is restored or purged
+func (s *CountryService) DeleteCountry(actx AuditContext, name string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var country models.Country
+		if err := tx.Where("LOWER(name) = LOWER(?)", name).Take(&country).Error; err != nil {
+			return err // gorm.ErrRecordNotFound indicates that no record was found to delete
+		}
+
+		now := time.Now().UTC()
+		err := tx.Model(&country).UpdateColumns(map[string]interface{}{
+			"deleted_at":    now,
+			"delete_reason": models.CountryDeletedManually,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete country %s: %w", name, err)
+		}
+		// End the country's history so as_of queries after now no longer see it
+		if err := closeSnapshot(tx, name, now); err != nil {
+			return err
+		}
+		return recordAudit(tx, actx, models.AuditCountryDelete, &country.Name, country, nil)
+	})
 }
 
 // GetTopCountriesByGDP fetches the top N countries by estimated GDP
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// snapshotColumns selects snapshot columns under the names models.Country expects
+const snapshotColumns = "country_id AS id, name, capital, region, population, currency_code, exchange_rate, estimated_gdp, gdp_estimator, gdp_estimator_params, flag_url, last_refreshed_at"
+
+// closeSnapshot ends the current version of the named country at validTo
+func closeSnapshot(tx *gorm.DB, name string, validTo time.Time) error {
+	err := tx.Model(&models.CountrySnapshot{}).
+		Where("LOWER(name) = LOWER(?) AND valid_to IS NULL", name).
+		Update("valid_to", validTo).Error
+	if err != nil {
+		return fmt.Errorf("failed to close snapshot of country %s: %w", name, err)
+	}
+	return nil
+}
+
+// snapshotsAsOf returns a query over the versions of every country that were current at asOf
+func (s *CountryService) snapshotsAsOf(asOf time.Time) *gorm.DB {
+	return s.db.Model(&models.CountrySnapshot{}).
+		Select(snapshotColumns).
+		Where("valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", asOf, asOf)
+}
+
+// BackfillSnapshots opens a snapshot for every country that has no current version,
+// such as rows cached before snapshots were recorded
+func (s *CountryService) BackfillSnapshots() error {
+	res := s.db.Exec(`
+		INSERT INTO country_snapshots
+			(country_id, name, capital, region, population, currency_code, exchange_rate, estimated_gdp, gdp_estimator, gdp_estimator_params, flag_url, last_refreshed_at, valid_from)
+		SELECT id, name, capital, region, population, currency_code, exchange_rate, estimated_gdp, gdp_estimator, gdp_estimator_params, flag_url, last_refreshed_at, last_refreshed_at
+		FROM countries c
+		WHERE c.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM country_snapshots cs
+			WHERE LOWER(cs.name) = LOWER(c.name) AND cs.valid_to IS NULL
+		)`)
+	if res.Error != nil {
+		return fmt.Errorf("failed to backfill country snapshots: %w", res.Error)
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("Backfilled snapshots for %d countries", res.RowsAffected)
+	}
+	return nil
+}
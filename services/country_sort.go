@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Where NULLs sort, for CountryFilter.Nulls
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// SortableCountryFields lists the columns countries can be sorted by
+var SortableCountryFields = []string{
+	"name", "capital", "region", "population", "currency_code", "exchange_rate", "estimated_gdp", "last_refreshed_at",
+}
+
+// legacySorts maps the original sort options to the equivalent field lists
+var legacySorts = map[string]string{
+	"gdp_desc":        "-estimated_gdp",
+	"gdp_asc":         "estimated_gdp",
+	"name_desc":       "-name",
+	"name_asc":        "name",
+	"population_desc": "-population",
+	"population_asc":  "population",
+}
+
+// defaultSort is the sort order when none is given
+const defaultSort = "name"
+
+// ValidateSort checks a sort specification and NULL placement.
+// sort is a comma-separated list of fields, each optionally prefixed with - for descending order,
+// or one of the original options such as gdp_desc. It returns nil if both are valid.
+func ValidateSort(sort, nulls string) map[string]string {
+	_, errs := parseSort(sort, nulls)
+	return errs
+}
+
+// parseSort turns a sort specification into sort keys, or returns the problems with it
+func parseSort(sort, nulls string) ([]sortKey, map[string]string) {
+	errs := make(map[string]string)
+	switch nulls {
+	case "", NullsFirst, NullsLast:
+	default:
+		errs["nulls"] = "must be first or last"
+	}
+
+	if sort == "" {
+		sort = defaultSort
+	}
+	if legacy, ok := legacySorts[sort]; ok {
+		sort = legacy
+	}
+
+	var keys []sortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		key := sortKey{Nulls: nulls}
+		switch {
+		case strings.HasPrefix(part, "-"):
+			key.Desc = true
+			part = part[1:]
+		case strings.HasPrefix(part, "+"):
+			part = part[1:]
+		}
+		key.Column = part
+
+		if !slices.Contains(SortableCountryFields, part) {
+			errs["sort"] = fmt.Sprintf("unknown sort field %q; allowed fields are %s", part, strings.Join(SortableCountryFields, ", "))
+			break
+		}
+		if seen[part] {
+			errs["sort"] = fmt.Sprintf("field %q is listed more than once", part)
+			break
+		}
+		seen[part] = true
+		keys = append(keys, key)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return keys, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	unknown := `unknown sort field %q; allowed fields are name, capital, region, population, currency_code, exchange_rate, estimated_gdp, last_refreshed_at`
+
+	tests := []struct {
+		name     string
+		sort     string
+		nulls    string
+		want     []sortKey
+		wantErrs map[string]string // nil if the sort is valid
+	}{
+		{name: "default", sort: "", want: []sortKey{{Column: "name"}}},
+		{name: "one field", sort: "population", want: []sortKey{{Column: "population"}}},
+		{name: "descending", sort: "-estimated_gdp", want: []sortKey{{Column: "estimated_gdp", Desc: true}}},
+		{name: "explicit ascending", sort: "+capital", want: []sortKey{{Column: "capital"}}},
+		{
+			name: "several fields",
+			sort: "region, -estimated_gdp,name",
+			want: []sortKey{{Column: "region"}, {Column: "estimated_gdp", Desc: true}, {Column: "name"}},
+		},
+		{name: "legacy gdp_desc", sort: "gdp_desc", want: []sortKey{{Column: "estimated_gdp", Desc: true}}},
+		{name: "legacy gdp_asc", sort: "gdp_asc", want: []sortKey{{Column: "estimated_gdp"}}},
+		{name: "legacy name_desc", sort: "name_desc", want: []sortKey{{Column: "name", Desc: true}}},
+		{name: "legacy name_asc", sort: "name_asc", want: []sortKey{{Column: "name"}}},
+		{name: "legacy population_desc", sort: "population_desc", want: []sortKey{{Column: "population", Desc: true}}},
+		{name: "legacy population_asc", sort: "population_asc", want: []sortKey{{Column: "population"}}},
+		{
+			name:  "nulls first",
+			sort:  "capital,-exchange_rate",
+			nulls: NullsFirst,
+			want:  []sortKey{{Column: "capital", Nulls: NullsFirst}, {Column: "exchange_rate", Desc: true, Nulls: NullsFirst}},
+		},
+		{
+			name:  "nulls last with a legacy sort",
+			sort:  "gdp_desc",
+			nulls: NullsLast,
+			want:  []sortKey{{Column: "estimated_gdp", Desc: true, Nulls: NullsLast}},
+		},
+		{name: "nulls with the default", nulls: NullsLast, want: []sortKey{{Column: "name", Nulls: NullsLast}}},
+		{
+			// Legacy options are only recognised on their own
+			name:     "legacy option in a list",
+			sort:     "region,gdp_desc",
+			wantErrs: map[string]string{"sort": fmt.Sprintf(unknown, "gdp_desc")},
+		},
+		{name: "unknown field", sort: "-flag_url", wantErrs: map[string]string{"sort": fmt.Sprintf(unknown, "flag_url")}},
+		{name: "wrong case", sort: "Name", wantErrs: map[string]string{"sort": fmt.Sprintf(unknown, "Name")}},
+		{name: "empty field", sort: "name,", wantErrs: map[string]string{"sort": fmt.Sprintf(unknown, "")}},
+		{name: "duplicate field", sort: "name,-name", wantErrs: map[string]string{"sort": `field "name" is listed more than once`}},
+		{name: "invalid nulls", sort: "name", nulls: "middle", wantErrs: map[string]string{"nulls": "must be first or last"}},
+		{
+			name:     "invalid sort and nulls",
+			sort:     "gdp",
+			nulls:    "FIRST",
+			wantErrs: map[string]string{"sort": fmt.Sprintf(unknown, "gdp"), "nulls": "must be first or last"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := parseSort(tt.sort, tt.nulls)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSort(%q, %q) keys = %+v, want %+v", tt.sort, tt.nulls, got, tt.want)
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("parseSort(%q, %q) errors = %v, want %v", tt.sort, tt.nulls, errs, tt.wantErrs)
+			}
+			if validated := ValidateSort(tt.sort, tt.nulls); !reflect.DeepEqual(validated, tt.wantErrs) {
+				t.Errorf("ValidateSort(%q, %q) = %v, want %v", tt.sort, tt.nulls, validated, tt.wantErrs)
+			}
+		})
+	}
+}
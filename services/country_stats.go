@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+
+	"stage-2/models"
+)
+
+// Groupings accepted by GetCountryStats
+const (
+	GroupByRegion   = "region"
+	GroupByCurrency = "currency"
+)
+
+// statsGroupColumns maps each grouping to the column it groups on
+var statsGroupColumns = map[string]string{
+	GroupByRegion:   "region",
+	GroupByCurrency: "currency_code", // The primary currency; GetCountryStats uses every currency when it can
+}
+
+// CountryGroupStats holds the statistics of the countries in one group. Countries whose grouping
+// column is NULL form a group whose Group is nil.
+type CountryGroupStats struct {
+	Group           *string  `json:"group"`
+	Count           int64    `json:"count"`
+	TotalPopulation int64    `json:"total_population"`
+	TotalGDP        *float64 `json:"total_estimated_gdp"`
+	AvgGDP          *float64 `json:"avg_estimated_gdp"`
+	MedianGDP       *float64 `json:"median_estimated_gdp"`
+	MinExchangeRate *float64 `json:"min_exchange_rate"`
+	MaxExchangeRate *float64 `json:"max_exchange_rate"`
+}
+
+// GetCountryStats aggregates the countries matching filter per region or per currency.
+// A country counts once in the group of every currency it uses, as the currency filter matches any of them,
+// except with AsOf, since snapshots only record the primary currency.
+// NULL values are ignored by every statistic but Count, and statistics with no values are nil.
+func (s *CountryService) GetCountryStats(filter CountryFilter, groupBy string) ([]CountryGroupStats, error) {
+	column, ok := statsGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown grouping %q", groupBy)
+	}
+
+	query := s.db.Model(&models.Country{})
+	if filter.AsOf != nil {
+		query = s.snapshotsAsOf(*filter.AsOf)
+	}
+	query = applyCountryFilter(query, filter)
+
+	rate := "exchange_rate"
+	if groupBy == GroupByCurrency && filter.AsOf == nil {
+		query = s.db.Table("(?) AS countries", query).
+			Joins("LEFT JOIN country_currencies cc ON cc.country_id = countries.id").
+			Joins("LEFT JOIN currencies cur ON cur.id = cc.currency_id")
+		column = "cur.code"
+		// exchange_rate is the rate of the primary currency, which may be overridden
+		rate = "CASE WHEN cur.code = countries.currency_code THEN countries.exchange_rate ELSE cur.latest_rate END"
+	}
+
+	stats := []CountryGroupStats{}
+	err := query.Select(column + ` AS "group",
+		COUNT(*) AS count,
+		COALESCE(SUM(population), 0)::bigint AS total_population,
+		SUM(estimated_gdp) AS total_gdp,
+		AVG(estimated_gdp) AS avg_gdp,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY estimated_gdp) AS median_gdp,
+		MIN(` + rate + `) AS min_exchange_rate,
+		MAX(` + rate + `) AS max_exchange_rate`).
+		Group(column).
+		Order(column + " ASC NULLS LAST").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute country stats by %s: %w", groupBy, err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"stage-2/models"
+)
+
+func TestGetCountryStatsByCurrency(t *testing.T) {
+	s := newReplayCountryService(t, openTestDB(t))
+	t.Chdir(t.TempDir())
+	if _, err := s.RefreshCountries(RefreshOptions{Trigger: models.RefreshTriggerManual, Audit: AuditContext{Actor: "test"}}); err != nil {
+		t.Fatalf("RefreshCountries() error = %v", err)
+	}
+	refreshed := time.Now().UTC()
+
+	// Zimbabwe uses ZWL, USD and ZAR, and Bhutan BTN and INR; both list the first one as primary
+	tests := []struct {
+		name       string
+		asOf       *time.Time
+		group      string
+		count      int64
+		population int64
+		rate       *float64 // Both the minimum and maximum
+	}{
+		{"primary and secondary currency", nil, "USD", 2, 329484123 + 14862927, floatPtr(1)},
+		{"secondary currency only", nil, "ZAR", 1, 14862927, floatPtr(17.38)},
+		{"primary currency", nil, "ZWL", 1, 14862927, floatPtr(26.6)},
+		{"secondary currency of a country without a primary rate", nil, "INR", 1, 771612, floatPtr(87.95)},
+		{"as of a time, by primary currency", &refreshed, "USD", 1, 329484123, floatPtr(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := s.GetCountryStats(CountryFilter{AsOf: tt.asOf}, GroupByCurrency)
+			if err != nil {
+				t.Fatalf("GetCountryStats() error = %v", err)
+			}
+			var group *CountryGroupStats
+			for i := range stats {
+				if stats[i].Group != nil && *stats[i].Group == tt.group {
+					group = &stats[i]
+				}
+			}
+			if group == nil {
+				t.Fatalf("GetCountryStats() has no %s group: %+v", tt.group, stats)
+			}
+			if group.Count != tt.count || group.TotalPopulation != tt.population {
+				t.Errorf("%s: count %d, population %d; want %d and %d", tt.group, group.Count, group.TotalPopulation, tt.count, tt.population)
+			}
+			if !equalPtr(group.MinExchangeRate, tt.rate) || !equalPtr(group.MaxExchangeRate, tt.rate) {
+				t.Errorf("%s: exchange rates %v to %v, want %v", tt.group,
+					derefFloat(group.MinExchangeRate), derefFloat(group.MaxExchangeRate), derefFloat(tt.rate))
+			}
+
+			// A group holds the countries the currency filter finds
+			if tt.asOf == nil {
+				result, err := s.GetCountries(CountryFilter{Currencies: []string{tt.group}}, Pagination{}, CountryProjection{})
+				if err != nil {
+					t.Fatalf("GetCountries() error = %v", err)
+				}
+				if result.Total != tt.count {
+					t.Errorf("currency=%s finds %d countries, want %d", tt.group, result.Total, tt.count)
+				}
+			}
+		})
+	}
+}
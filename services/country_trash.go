@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// TrashedCountry is a soft-deleted country along with when and why it was deleted
+type TrashedCountry struct {
+	models.Country
+	DeletedAt    time.Time `json:"deleted_at"`
+	DeleteReason string    `json:"delete_reason"`
+}
+
+// loadUnrestorableKeys returns the nameKeys of soft-deleted countries that a refresh must not bring back
+func (s *CountryService) loadUnrestorableKeys() (map[string]bool, error) {
+	keys := make(map[string]bool)
+	if s.refreshConfig.ResurrectDeleted {
+		return keys, nil
+	}
+
+	var names []string
+	err := s.db.Unscoped().Model(&models.Country{}).
+		Where("deleted_at IS NOT NULL AND delete_reason = ?", models.CountryDeletedManually).
+		Pluck("name", &names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deleted countries: %w", err)
+	}
+	for _, name := range names {
+		keys[nameKey(name)] = true
+	}
+	return keys, nil
+}
+
+// GetTrash fetches every soft-deleted country, most recently deleted first
+func (s *CountryService) GetTrash() ([]TrashedCountry, error) {
+	var countries []models.Country
+	err := s.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC, name ASC").Find(&countries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deleted countries: %w", err)
+	}
+
+	trash := make([]TrashedCountry, len(countries))
+	for i, country := range countries {
+		trash[i] = TrashedCountry{Country: country, DeletedAt: country.DeletedAt.Time}
+		if country.DeleteReason != nil {
+			trash[i].DeleteReason = *country.DeleteReason
+		}
+	}
+	return trash, nil
+}
+
+// RestoreCountry brings a soft-deleted country back and starts a new version of it.
+// It returns gorm.ErrRecordNotFound if the country is not in the trash.
+func (s *CountryService) RestoreCountry(actx AuditContext, name string) (*models.Country, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var country models.Country
+		err := tx.Unscoped().Where("LOWER(name) = LOWER(?) AND deleted_at IS NOT NULL", name).Take(&country).Error
+		if err != nil {
+			return err
+		}
+		before := TrashedCountry{Country: country, DeletedAt: country.DeletedAt.Time}
+		if country.DeleteReason != nil {
+			before.DeleteReason = *country.DeleteReason
+		}
+
+		err = tx.Unscoped().Model(&country).UpdateColumns(map[string]interface{}{
+			"deleted_at":    nil,
+			"delete_reason": nil,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to restore country %s: %w", country.Name, err)
+		}
+
+		snapshot := models.NewCountrySnapshot(&country, time.Now().UTC(), nil)
+		if err := tx.Create(&snapshot).Error; err != nil {
+			return fmt.Errorf("failed to record snapshot of country %s: %w", country.Name, err)
+		}
+
+		return recordAudit(tx, actx, models.AuditCountryRestore, &country.Name, before, country)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCountryByName(name, nil, CountryProjection{})
+}
+
+// PurgeCountry permanently deletes a country from the trash, along with its overrides and currency links.
+// Its snapshots are kept for as_of queries. It returns gorm.ErrRecordNotFound if the country is not in the trash.
+func (s *CountryService) PurgeCountry(actx AuditContext, name string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var country models.Country
+		err := tx.Unscoped().Where("LOWER(name) = LOWER(?) AND deleted_at IS NOT NULL", name).Take(&country).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Delete(&country).Error; err != nil {
+			return fmt.Errorf("failed to purge country %s: %w", country.Name, err)
+		}
+		return recordAudit(tx, actx, models.AuditCountryPurge, &country.Name, country, nil)
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsertBatchSize is how many rows each batched statement writes
+const upsertBatchSize = 100
+
+// countryUpdateColumns are overwritten when a refreshed country already exists
+var countryUpdateColumns = []string{
+	"name", "capital", "region", "population", "currency_code", "exchange_rate",
+	"estimated_gdp", "gdp_estimator", "gdp_estimator_params", "flag_url", "alt_names", "last_refreshed_at",
+	// A country that is back in the feed is no longer stale or removed, and
+	// a manually created country that shows up upstream is managed by refreshes from then on
+	"stale", "deleted_at", "delete_reason", "origin",
+}
+
+// nameKey normalizes a country name the same way as the idx_countries_name_key index
+func nameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// upsertCountries writes refreshed countries with batched INSERT ... ON CONFLICT statements keyed on the
+// case-insensitive name, then updates their snapshots and currency links. It fills in the IDs of countries,
+// updates the counts on run and returns the per-country change log.
+// currencyCodes[i] lists the currency codes of countries[i].
+func upsertCountries(tx *gorm.DB, run *models.RefreshRun, countries []models.Country, currencyCodes [][]string,
+	currencies map[string]*models.Currency, now time.Time, report RefreshProgressFunc) ([]models.CountryChange, error) {
+	// Load every existing row in one query so diffs can be computed in memory
+	keys := make([]string, len(countries))
+	for i := range countries {
+		keys[i] = nameKey(countries[i].Name)
+	}
+	var existingRows []models.Country
+	if err := tx.Unscoped().Where("LOWER(name) IN ?", keys).Find(&existingRows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load existing countries: %w", err)
+	}
+	existing := make(map[string]*models.Country, len(existingRows))
+	for i := range existingRows {
+		existing[nameKey(existingRows[i].Name)] = &existingRows[i]
+	}
+
+	var (
+		changes     []models.CountryChange
+		changedKeys []string
+		// changed holds the indexes of countries that need a new snapshot
+		changed []int
+	)
+	for i := range countries {
+		country := &countries[i]
+		before, ok := existing[keys[i]]
+		// A soft-deleted country that reappears upstream is restored and counts as inserted
+		if !ok || before.DeletedAt.Valid {
+			run.Inserted++
+			changes = append(changes, models.CountryChange{
+				RefreshRunID: run.ID,
+				CountryName:  country.Name,
+				ChangeType:   models.CountryChangeInserted,
+				Changes:      diffCountries(&models.Country{}, country),
+			})
+			changed = append(changed, i)
+			continue
+		}
+
+		diff := diffCountries(before, country)
+		if len(diff) == 0 {
+			run.Unchanged++
+			continue
+		}
+		run.Updated++
+		changes = append(changes, models.CountryChange{
+			RefreshRunID: run.ID,
+			CountryName:  country.Name,
+			ChangeType:   models.CountryChangeUpdated,
+			Changes:      diff,
+		})
+		changed = append(changed, i)
+		changedKeys = append(changedKeys, keys[i])
+	}
+
+	// Unchanged rows are written too, so their last_refreshed_at moves forward
+	for start := 0; start < len(countries); start += upsertBatchSize {
+		end := min(start+upsertBatchSize, len(countries))
+		batch := countries[start:end]
+		err := tx.Omit("Currencies", "Overrides").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "(LOWER(name))", Raw: true}},
+			DoUpdates: clause.AssignmentColumns(countryUpdateColumns),
+		}).Create(&batch).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert countries: %w", err)
+		}
+		if report != nil {
+			report(models.RefreshPhaseSaving, end, len(countries))
+		}
+	}
+
+	// Start a new version of every inserted or changed country
+	if len(changedKeys) > 0 {
+		err := tx.Model(&models.CountrySnapshot{}).
+			Where("valid_to IS NULL AND LOWER(name) IN ?", changedKeys).
+			Update("valid_to", now).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to close country snapshots: %w", err)
+		}
+	}
+	if len(changed) > 0 {
+		snapshots := make([]models.CountrySnapshot, 0, len(changed))
+		for _, i := range changed {
+			snapshots = append(snapshots, models.NewCountrySnapshot(&countries[i], now, &run.ID))
+		}
+		if err := tx.CreateInBatches(&snapshots, upsertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to record country snapshots: %w", err)
+		}
+	}
+
+	if err := replaceCurrencyLinks(tx, countries, currencyCodes, currencies); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// replaceCurrencyLinks replaces the country_currencies rows of every refreshed country in two statements
+func replaceCurrencyLinks(tx *gorm.DB, countries []models.Country, currencyCodes [][]string, currencies map[string]*models.Currency) error {
+	if len(countries) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(countries))
+	var links []map[string]interface{}
+	for i := range countries {
+		ids[i] = countries[i].ID
+		seen := make(map[string]bool)
+		for _, code := range currencyCodes[i] {
+			if seen[code] {
+				continue
+			}
+			seen[code] = true
+			links = append(links, map[string]interface{}{
+				"country_id":  countries[i].ID,
+				"currency_id": currencies[code].ID,
+			})
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM country_currencies WHERE country_id IN ?", ids).Error; err != nil {
+		return fmt.Errorf("failed to clear currency links: %w", err)
+	}
+	if len(links) > 0 {
+		if err := tx.Table("country_currencies").CreateInBatches(&links, 500).Error; err != nil {
+			return fmt.Errorf("failed to link currencies to countries: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stage-2/config"
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// benchmarkCountryCount is about the size of the restcountries.com feed
+const benchmarkCountryCount = 250
+
+// errRollback ends a benchmark iteration's transaction, so every iteration starts from the same rows
+var errRollback = errors.New("rollback")
+
+// benchmarkCountries returns n refreshed countries with one currency each. Countries of different
+// generations differ in population, so upserting one generation over another updates every row.
+func benchmarkCountries(n, generation int, now time.Time) ([]models.Country, [][]string) {
+	countries := make([]models.Country, n)
+	codes := make([][]string, n)
+	for i := range countries {
+		code := fmt.Sprintf("C%02d", i%40)
+		capital := fmt.Sprintf("Capital %d", i)
+		region := []string{"Africa", "Americas", "Asia", "Europe", "Oceania"}[i%5]
+		population := uint64(1_000_000 + i*1000 + generation)
+		rate := float64(i%40 + 1)
+		gdp := float64(population) * 1500 / rate
+		countries[i] = models.Country{
+			Name:            fmt.Sprintf("Country %03d", i),
+			Capital:         &capital,
+			Region:          &region,
+			Population:      population,
+			CurrencyCode:    &code,
+			ExchangeRate:    &rate,
+			EstimatedGDP:    &gdp,
+			LastRefreshedAt: now,
+			Origin:          models.CountryOriginUpstream,
+		}
+		codes[i] = []string{code}
+	}
+	return countries, codes
+}
+
+// upsertCountriesPerRow is the persistence path upsertCountries replaced, as it was before commit 6db13ea:
+// a case-insensitive SELECT and a Create or Save for each country, with its snapshot and currency links
+// written one by one. Only the manual rollbacks are left out, since the benchmark rolls back every iteration.
+func upsertCountriesPerRow(tx *gorm.DB, run *models.RefreshRun, processedCountries []models.Country, currencyCodes [][]string,
+	currencies map[string]*models.Currency, now time.Time) ([]models.CountryChange, error) {
+	var changes []models.CountryChange
+	for i, country := range processedCountries {
+		var existingCountry models.Country
+		// Case-insensitive comparison for name
+		res := tx.Where("LOWER(name) = LOWER(?)", country.Name).First(&existingCountry)
+
+		if res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				// Insert new record
+				run.Inserted++
+				changes = append(changes, models.CountryChange{
+					RefreshRunID: run.ID,
+					CountryName:  country.Name,
+					ChangeType:   models.CountryChangeInserted,
+					Changes:      diffCountries(&models.Country{}, &country),
+				})
+				if err := tx.Create(&country).Error; err != nil {
+					return nil, fmt.Errorf("failed to insert country %s: %w", country.Name, err)
+				}
+				if err := openSnapshot(tx, &country, now, &run.ID); err != nil {
+					return nil, err
+				}
+			} else {
+				// Other database error
+				return nil, fmt.Errorf("database error checking country %s: %w", country.Name, res.Error)
+			}
+		} else {
+			// Update existing record
+			diff := diffCountries(&existingCountry, &country)
+			if len(diff) > 0 {
+				run.Updated++
+				changes = append(changes, models.CountryChange{
+					RefreshRunID: run.ID,
+					CountryName:  country.Name,
+					ChangeType:   models.CountryChangeUpdated,
+					Changes:      diff,
+				})
+			} else {
+				run.Unchanged++
+			}
+			country.ID = existingCountry.ID // Preserve ID for update
+			if err := tx.Save(&country).Error; err != nil {
+				return nil, fmt.Errorf("failed to update country %s: %w", country.Name, err)
+			}
+			// Start a new version only when something changed
+			if len(diff) > 0 {
+				if err := closeSnapshot(tx, country.Name, now); err != nil {
+					return nil, err
+				}
+				if err := openSnapshot(tx, &country, now, &run.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := linkCurrencies(tx, &country, currencyCodes[i], currencies); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// openSnapshot records country as the current version from validFrom onwards, as before commit 6db13ea
+func openSnapshot(tx *gorm.DB, country *models.Country, validFrom time.Time, runID *uint) error {
+	snapshot := models.NewCountrySnapshot(country, validFrom, runID)
+	if err := tx.Create(&snapshot).Error; err != nil {
+		return fmt.Errorf("failed to record snapshot of country %s: %w", country.Name, err)
+	}
+	return nil
+}
+
+// linkCurrencies replaces the currencies linked to country with those in codes, as before commit 6db13ea
+func linkCurrencies(tx *gorm.DB, country *models.Country, codes []string, currencies map[string]*models.Currency) error {
+	linked := make([]models.Currency, 0, len(codes))
+	for _, code := range codes {
+		linked = append(linked, *currencies[code])
+	}
+
+	association := tx.Model(country).Association("Currencies")
+	var err error
+	if len(linked) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(linked)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link currencies to country %s: %w", country.Name, err)
+	}
+	return nil
+}
+
+// countStatements counts the statements db sends to the database, each a round trip
+func countStatements(tb testing.TB, db *gorm.DB) *atomic.Int64 {
+	tb.Helper()
+	var count atomic.Int64
+	increment := func(*gorm.DB) { count.Add(1) }
+	callbacks := db.Callback()
+	for name, err := range map[string]error{
+		"create": callbacks.Create().After("gorm:create").Register("test:count_create", increment),
+		"query":  callbacks.Query().After("gorm:query").Register("test:count_query", increment),
+		"update": callbacks.Update().After("gorm:update").Register("test:count_update", increment),
+		"delete": callbacks.Delete().After("gorm:delete").Register("test:count_delete", increment),
+		"row":    callbacks.Row().After("gorm:row").Register("test:count_row", increment),
+		"raw":    callbacks.Raw().After("gorm:raw").Register("test:count_raw", increment),
+	} {
+		if err != nil {
+			tb.Fatalf("failed to register %s statement counter: %v", name, err)
+		}
+	}
+	return &count
+}
+
+// upsertFunc is a country persistence path under benchmark
+type upsertFunc func(tx *gorm.DB, run *models.RefreshRun, countries []models.Country, currencyCodes [][]string,
+	currencies map[string]*models.Currency, now time.Time) ([]models.CountryChange, error)
+
+// BenchmarkUpsertCountries compares writing a refresh row by row with the batched ON CONFLICT upsert,
+// both into an empty table and over a previous refresh that every country differs from.
+// Besides the time, it reports the statements each upsert sends as statements/op.
+// It needs Postgres: set TEST_DATABASE_URL.
+func BenchmarkUpsertCountries(b *testing.B) {
+	db := openTestDB(b)
+	now := time.Now().UTC()
+
+	currencies := make(map[string]*models.Currency)
+	for i := 0; i < 40; i++ {
+		code := fmt.Sprintf("C%02d", i)
+		currencies[code] = &models.Currency{Code: code, Name: strPtr("Currency " + code)}
+	}
+	if err := upsertCurrencies(db, currencies); err != nil {
+		b.Fatal(err)
+	}
+
+	paths := []struct {
+		name   string
+		upsert upsertFunc
+	}{
+		{"per_row", upsertCountriesPerRow},
+		{"batched", func(tx *gorm.DB, run *models.RefreshRun, countries []models.Country, currencyCodes [][]string,
+			currencies map[string]*models.Currency, now time.Time) ([]models.CountryChange, error) {
+			return upsertCountries(tx, run, countries, currencyCodes, currencies, now, nil)
+		}},
+	}
+
+	statements := countStatements(b, db)
+	run := func(b *testing.B, upsert upsertFunc, generation int) {
+		var upsertStatements int64
+		for b.Loop() {
+			countries, codes := benchmarkCountries(benchmarkCountryCount, generation, now)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				before := statements.Load()
+				if _, err := upsert(tx, &models.RefreshRun{}, countries, codes, currencies, now); err != nil {
+					return err
+				}
+				upsertStatements += statements.Load() - before
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(upsertStatements)/float64(b.N), "statements/op")
+	}
+
+	for _, path := range paths {
+		b.Run("insert/"+path.name, func(b *testing.B) {
+			run(b, path.upsert, 1)
+		})
+	}
+
+	// Seed the previous refresh that the update benchmarks overwrite
+	seed, seedCodes := benchmarkCountries(benchmarkCountryCount, 0, now)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := upsertCountries(tx, &models.RefreshRun{}, seed, seedCodes, currencies, now, nil)
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, path := range paths {
+		b.Run("update/"+path.name, func(b *testing.B) {
+			run(b, path.upsert, 1)
+		})
+	}
+}
+
+func TestCountryNameUniqueIgnoringCase(t *testing.T) {
+	db := openTestDB(t)
+
+	// A schema migrated before the unique tag was dropped still has the case-sensitive constraint
+	if err := db.Exec("ALTER TABLE countries ADD CONSTRAINT uni_countries_name UNIQUE (name)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := config.ApplySchemaMigrations(db); err != nil {
+		t.Fatalf("ApplySchemaMigrations() error = %v", err)
+	}
+	var constraints int64
+	err := db.Raw("SELECT COUNT(*) FROM pg_constraint WHERE conrelid = 'countries'::regclass AND contype = 'u'").Scan(&constraints).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if constraints != 0 {
+		t.Errorf("countries has %d unique constraints after the migration, want 0", constraints)
+	}
+
+	// idx_countries_name_key alone keeps names unique
+	if err := db.Create(&models.Country{Name: "Nigeria", Population: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Country{Name: "NIGERIA", Population: 1}).Error; err == nil {
+		t.Error("created a country whose name differs from an existing one only in case")
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// manualEstimator is recorded as the GDP estimator of countries whose estimated_gdp was set through the API
+const manualEstimator = "manual"
+
+// ErrCountryExists is returned when a write would give two countries the same case-insensitive name
+var ErrCountryExists = errors.New("a country with this name already exists")
+
+// ErrCountryInTrash is returned when a write would reuse the name of a soft-deleted country
+var ErrCountryInTrash = errors.New("a deleted country with this name is in the trash")
+
+// CreateCountry stores a country created through the API. exchange_rate and estimated_gdp are
+// derived from the stored rates and the configured GDP estimator if they are not set.
+// It returns ErrCountryExists or ErrCountryInTrash if the name is taken.
+func (s *CountryService) CreateCountry(actx AuditContext, input *models.Country) (*models.Country, error) {
+	country := models.Country{Origin: models.CountryOriginManual}
+	copyWritableFields(&country, input)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkNameAvailable(tx, country.Name, 0); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := s.fillDerivedFields(tx, &country, input, nil, nil, now); err != nil {
+			return err
+		}
+		if err := tx.Omit("Currencies", "Overrides").Create(&country).Error; err != nil {
+			return fmt.Errorf("failed to create country %s: %w", country.Name, err)
+		}
+		if err := recordManualVersion(tx, &country, "", true, now); err != nil {
+			return err
+		}
+		return recordAudit(tx, actx, models.AuditCountryCreate, &country.Name, nil, country)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCountryByName(country.Name, nil, CountryProjection{})
+}
+
+// UpdateCountry replaces the writable fields of the named country, which may rename it. exchange_rate and
+// estimated_gdp are derived again when input leaves them unset, except that the ones listed in keep keep their
+// stored values while what they are derived from is unchanged.
+// It returns gorm.ErrRecordNotFound if the country doesn't exist, and ErrCountryExists or ErrCountryInTrash
+// if the new name is taken.
+func (s *CountryService) UpdateCountry(actx AuditContext, name string, input *models.Country, keep ...string) (*models.Country, error) {
+	var country models.Country
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("LOWER(name) = LOWER(?)", name).Take(&country).Error; err != nil {
+			return err
+		}
+		if err := checkNameAvailable(tx, input.Name, country.ID); err != nil {
+			return err
+		}
+
+		before := country
+		previousName := country.Name
+		previousCurrency := derefString(country.CurrencyCode)
+		copyWritableFields(&country, input)
+		now := time.Now().UTC()
+		if err := s.fillDerivedFields(tx, &country, input, &before, keep, now); err != nil {
+			return err
+		}
+		err := tx.Model(&country).Select("name", "capital", "region", "population", "currency_code", "exchange_rate",
+			"estimated_gdp", "gdp_estimator", "gdp_estimator_params", "flag_url", "last_refreshed_at").Updates(&country).Error
+		if err != nil {
+			return fmt.Errorf("failed to update country %s: %w", previousName, err)
+		}
+		relink := derefString(country.CurrencyCode) != previousCurrency
+		if err := recordManualVersion(tx, &country, previousName, relink, now); err != nil {
+			return err
+		}
+		return recordAudit(tx, actx, models.AuditCountryUpdate, &country.Name, before, country)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCountryByName(country.Name, nil, CountryProjection{})
+}
+
+// checkNameAvailable returns ErrCountryExists if a country other than excludeID already uses name,
+// or ErrCountryInTrash if a soft-deleted country still holds it
+func checkNameAvailable(tx *gorm.DB, name string, excludeID uint) error {
+	var holders []models.Country
+	err := tx.Unscoped().Where("LOWER(name) = LOWER(?) AND id <> ?", name, excludeID).Limit(1).Find(&holders).Error
+	if err != nil {
+		return fmt.Errorf("failed to check country name %s: %w", name, err)
+	}
+	if len(holders) == 0 {
+		return nil
+	}
+	if holders[0].DeletedAt.Valid {
+		return ErrCountryInTrash
+	}
+	return ErrCountryExists
+}
+
+// copyWritableFields copies the fields a client may set from src to dst
+func copyWritableFields(dst, src *models.Country) {
+	dst.Name = src.Name
+	dst.Capital = src.Capital
+	dst.Region = src.Region
+	dst.Population = src.Population
+	dst.CurrencyCode = src.CurrencyCode
+	dst.ExchangeRate = src.ExchangeRate
+	dst.EstimatedGDP = src.EstimatedGDP
+	dst.FlagURL = src.FlagURL
+}
+
+// fillDerivedFields looks up the exchange rate and estimates GDP when input leaves them unset,
+// and records how estimated_gdp was produced. For an update, previous is the stored country and the
+// derived fields listed in keep are copied from it instead: exchange_rate while currency_code is unchanged,
+// and estimated_gdp while population and exchange_rate are.
+func (s *CountryService) fillDerivedFields(tx *gorm.DB, country, input, previous *models.Country, keep []string, now time.Time) error {
+	country.LastRefreshedAt = now
+
+	if input.ExchangeRate == nil {
+		if previous != nil && slices.Contains(keep, "exchange_rate") &&
+			derefString(previous.CurrencyCode) == derefString(country.CurrencyCode) {
+			country.ExchangeRate = previous.ExchangeRate
+		} else {
+			rate, err := latestRate(tx, country.CurrencyCode)
+			if err != nil {
+				return err
+			}
+			country.ExchangeRate = rate
+		}
+	}
+
+	if input.EstimatedGDP != nil {
+		estimator := manualEstimator
+		country.GDPEstimator = &estimator
+		country.GDPEstimatorParams = nil
+		return nil
+	}
+	if previous != nil && slices.Contains(keep, "estimated_gdp") && previous.Population == country.Population &&
+		derefFloat(previous.ExchangeRate) == derefFloat(country.ExchangeRate) {
+		country.EstimatedGDP = previous.EstimatedGDP
+		country.GDPEstimator = previous.GDPEstimator
+		country.GDPEstimatorParams = previous.GDPEstimatorParams
+		return nil
+	}
+	estimate := s.gdpEstimator.Estimate(GDPInput{
+		Name:         country.Name,
+		Population:   country.Population,
+		ExchangeRate: country.ExchangeRate,
+		RefreshedAt:  now,
+	})
+	country.EstimatedGDP = estimate.Value
+	country.GDPEstimator = nil
+	country.GDPEstimatorParams = nil
+	if estimate.Value != nil {
+		country.GDPEstimator = &estimate.Estimator
+		country.GDPEstimatorParams = estimate.Params
+	}
+	return nil
+}
+
+// latestRate returns the latest stored USD rate of a currency, or nil if the code is nil or unknown
+func latestRate(tx *gorm.DB, code *string) (*float64, error) {
+	if code == nil {
+		return nil, nil
+	}
+	var currency models.Currency
+	if err := tx.Where("code = ?", *code).Limit(1).Find(&currency).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up currency %s: %w", *code, err)
+	}
+	return currency.LatestRate, nil
+}
+
+// recordManualVersion starts a new snapshot of a written country and, if relink is set, links it to its primary
+// currency only. previousName is the name the current snapshot was recorded under, or empty for a new country.
+func recordManualVersion(tx *gorm.DB, country *models.Country, previousName string, relink bool, now time.Time) error {
+	if relink {
+		if err := linkPrimaryCurrency(tx, country); err != nil {
+			return err
+		}
+	}
+
+	if previousName != "" {
+		if err := closeSnapshot(tx, previousName, now); err != nil {
+			return err
+		}
+	}
+	snapshot := models.NewCountrySnapshot(country, now, nil)
+	if err := tx.Create(&snapshot).Error; err != nil {
+		return fmt.Errorf("failed to record snapshot of country %s: %w", country.Name, err)
+	}
+	return nil
+}
+
+// linkPrimaryCurrency replaces the currency links of country with its primary currency
+func linkPrimaryCurrency(tx *gorm.DB, country *models.Country) error {
+	var codes []string
+	currencies := make(map[string]*models.Currency)
+	if country.CurrencyCode != nil {
+		var currency models.Currency
+		res := tx.Where("code = ?", *country.CurrencyCode).Limit(1).Find(&currency)
+		if res.Error != nil {
+			return fmt.Errorf("failed to look up currency %s: %w", *country.CurrencyCode, res.Error)
+		}
+		// Unknown currencies are linked once a refresh fetches them
+		if res.RowsAffected > 0 {
+			codes = append(codes, currency.Code)
+			currencies[currency.Code] = &currency
+		}
+	}
+	return replaceCurrencyLinks(tx, []models.Country{*country}, [][]string{codes}, currencies)
+}
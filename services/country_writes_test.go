@@ -0,0 +1,107 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"stage-2/config"
+	"stage-2/models"
+)
+
+func TestFillDerivedFieldsKeep(t *testing.T) {
+	now := time.Date(2025, 10, 22, 18, 0, 0, 0, time.UTC)
+	estimator, err := NewGDPEstimator(config.GDPConfig{Estimator: config.GDPEstimatorFixed, Multiplier: config.DefaultGDPMultiplier})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &CountryService{gdpEstimator: estimator}
+
+	// A stored country without a currency, so no test needs to look up a rate
+	stored := models.Country{
+		Name:               "Atlantis",
+		Population:         1000,
+		ExchangeRate:       floatPtr(2),
+		EstimatedGDP:       floatPtr(12345),
+		GDPEstimator:       strPtr(config.GDPEstimatorSeeded),
+		GDPEstimatorParams: map[string]interface{}{"seed": "atlantis|2025-10-01", "multiplier": 1234.0},
+	}
+
+	tests := []struct {
+		name          string
+		input         models.Country // The writable fields after the patch
+		keep          []string
+		wantRate      *float64
+		wantGDP       *float64
+		wantEstimator *string
+		wantParams    map[string]interface{}
+	}{
+		{
+			name:          "unrelated field changed",
+			input:         models.Country{Name: "Atlantis", Capital: strPtr("Poseidonia"), Population: 1000},
+			keep:          []string{"exchange_rate", "estimated_gdp"},
+			wantRate:      floatPtr(2),
+			wantGDP:       floatPtr(12345),
+			wantEstimator: strPtr(config.GDPEstimatorSeeded),
+			wantParams:    stored.GDPEstimatorParams,
+		},
+		{
+			name:          "population changed",
+			input:         models.Country{Name: "Atlantis", Population: 2000},
+			keep:          []string{"exchange_rate", "estimated_gdp"},
+			wantRate:      floatPtr(2),
+			wantGDP:       floatPtr(2000 * config.DefaultGDPMultiplier / 2),
+			wantEstimator: strPtr(config.GDPEstimatorFixed),
+			wantParams:    map[string]interface{}{"multiplier": config.DefaultGDPMultiplier},
+		},
+		{
+			name:          "exchange rate set",
+			input:         models.Country{Name: "Atlantis", Population: 1000, ExchangeRate: floatPtr(4)},
+			keep:          []string{"estimated_gdp"},
+			wantRate:      floatPtr(4),
+			wantGDP:       floatPtr(1000 * config.DefaultGDPMultiplier / 4),
+			wantEstimator: strPtr(config.GDPEstimatorFixed),
+			wantParams:    map[string]interface{}{"multiplier": config.DefaultGDPMultiplier},
+		},
+		{
+			name:          "estimated gdp set",
+			input:         models.Country{Name: "Atlantis", Population: 1000, EstimatedGDP: floatPtr(999)},
+			keep:          []string{"exchange_rate"},
+			wantRate:      floatPtr(2),
+			wantGDP:       floatPtr(999),
+			wantEstimator: strPtr(manualEstimator),
+		},
+		{
+			// Without a currency there is no rate to look up, and so no estimate
+			name:  "both derived again",
+			input: models.Country{Name: "Atlantis", Population: 1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// UpdateCountry starts from the stored row with the writable fields copied over
+			previous := stored
+			country := stored
+			copyWritableFields(&country, &tt.input)
+
+			if err := s.fillDerivedFields(nil, &country, &tt.input, &previous, tt.keep, now); err != nil {
+				t.Fatalf("fillDerivedFields() error = %v", err)
+			}
+			if !equalPtr(country.ExchangeRate, tt.wantRate) {
+				t.Errorf("exchange_rate = %v, want %v", derefFloat(country.ExchangeRate), derefFloat(tt.wantRate))
+			}
+			if !equalPtr(country.EstimatedGDP, tt.wantGDP) {
+				t.Errorf("estimated_gdp = %v, want %v", derefFloat(country.EstimatedGDP), derefFloat(tt.wantGDP))
+			}
+			if !equalPtr(country.GDPEstimator, tt.wantEstimator) {
+				t.Errorf("gdp_estimator = %v, want %v", derefString(country.GDPEstimator), derefString(tt.wantEstimator))
+			}
+			if !reflect.DeepEqual(country.GDPEstimatorParams, tt.wantParams) {
+				t.Errorf("gdp_estimator_params = %v, want %v", country.GDPEstimatorParams, tt.wantParams)
+			}
+			if !country.LastRefreshedAt.Equal(now) {
+				t.Errorf("last_refreshed_at = %v, want %v", country.LastRefreshedAt, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CurrencyService handles business logic related to currencies
+type CurrencyService struct {
+	db *gorm.DB
+}
+
+// NewCurrencyService creates a new CurrencyService
+func NewCurrencyService(db *gorm.DB) *CurrencyService {
+	return &CurrencyService{db: db}
+}
+
+// GetCurrencies fetches all currencies ordered by code
+func (s *CurrencyService) GetCurrencies() ([]models.Currency, error) {
+	var currencies []models.Currency
+	if err := s.db.Order("code ASC").Find(&currencies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch currencies: %w", err)
+	}
+	return currencies, nil
+}
+
+// GetCurrencyByCode fetches a single currency and the countries that use it
+func (s *CurrencyService) GetCurrencyByCode(code string) (*models.Currency, error) {
+	var currency models.Currency
+	err := s.db.Preload("Countries", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name ASC")
+	}).Where("code = ?", strings.ToUpper(code)).First(&currency).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Currency not found
+		}
+		return nil, fmt.Errorf("failed to fetch currency %s: %w", code, err)
+	}
+	return &currency, nil
+}
+
+// newCurrency builds a Currency from provider data and the latest fetched rates
+func newCurrency(pc ProviderCurrency, rates map[string]float64, fetchedAt time.Time) *models.Currency {
+	currency := &models.Currency{Code: pc.Code}
+	if pc.Name != "" {
+		name := pc.Name
+		currency.Name = &name
+	}
+	if pc.Symbol != "" {
+		symbol := pc.Symbol
+		currency.Symbol = &symbol
+	}
+	if rate, ok := rates[pc.Code]; ok {
+		currency.LatestRate = &rate
+		currency.RateUpdatedAt = &fetchedAt
+	}
+	return currency
+}
+
+// upsertCurrencies inserts or updates currencies by code and fills in their IDs
+func upsertCurrencies(tx *gorm.DB, currencies map[string]*models.Currency) error {
+	if len(currencies) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	rows := make([]models.Currency, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, *currencies[code])
+	}
+
+	err := tx.Omit("Countries").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "symbol", "latest_rate", "rate_updated_at"}),
+	}).Create(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to upsert currencies: %w", err)
+	}
+
+	for i := range rows {
+		currencies[rows[i].Code].ID = rows[i].ID
+	}
+	return nil
+}
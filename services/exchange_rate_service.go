@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stage-2/config"
+
+	"gorm.io/gorm"
+)
+
+// RateIntervals maps the supported time-series intervals to their date_trunc field
+var RateIntervals = map[string]string{
+	"hour":  "hour",
+	"day":   "day",
+	"week":  "week",
+	"month": "month",
+}
+
+// RatePoint is a single value in an exchange rate time series
+type RatePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Rate      float64   `json:"rate"`
+}
+
+// RateSummary holds the statistics of an exchange rate time series
+type RateSummary struct {
+	Min           *float64 `json:"min"`
+	Max           *float64 `json:"max"`
+	Average       *float64 `json:"average"`
+	PercentChange *float64 `json:"percent_change"` // From the first to the last fetched rate in the range
+}
+
+// RateSeries is the exchange rate history of a currency over a time range
+type RateSeries struct {
+	CurrencyCode string      `json:"currency_code"`
+	From         time.Time   `json:"from"`
+	To           time.Time   `json:"to"`
+	Interval     string      `json:"interval,omitempty"`
+	Points       []RatePoint `json:"points"`
+	Summary      RateSummary `json:"summary"`
+}
+
+// ExchangeRateService handles business logic related to exchange rate history and conversions
+type ExchangeRateService struct {
+	db         *gorm.DB
+	rateConfig config.RateConfig
+}
+
+// NewExchangeRateService creates a new ExchangeRateService
+func NewExchangeRateService(db *gorm.DB, rateConfig config.RateConfig) *ExchangeRateService {
+	return &ExchangeRateService{db: db, rateConfig: rateConfig}
+}
+
+// GetRateSeries returns the USD rate history of a currency between from and to.
+// If interval is set, rates are averaged per interval bucket. It returns nil if the currency has never been fetched.
+func (s *ExchangeRateService) GetRateSeries(code string, from, to time.Time, interval string) (*RateSeries, error) {
+	code = strings.ToUpper(code)
+
+	var known int64
+	if err := s.db.Table("exchange_rates").Where("currency_code = ?", code).Limit(1).Count(&known).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up currency %s: %w", code, err)
+	}
+	if known == 0 {
+		return nil, nil // Currency not found
+	}
+
+	series := &RateSeries{
+		CurrencyCode: code,
+		From:         from,
+		To:           to,
+		Interval:     interval,
+		Points:       []RatePoint{},
+	}
+
+	inRange := s.db.Table("exchange_rates").Where("currency_code = ? AND fetched_at BETWEEN ? AND ?", code, from, to)
+
+	var err error
+	if interval == "" {
+		err = inRange.Select("fetched_at AS timestamp, rate").Order("fetched_at ASC").Scan(&series.Points).Error
+	} else {
+		field, ok := RateIntervals[interval]
+		if !ok {
+			return nil, fmt.Errorf("unsupported interval %q", interval)
+		}
+		bucket := fmt.Sprintf("date_trunc('%s', fetched_at)", field)
+		err = inRange.Select(bucket + " AS timestamp, AVG(rate) AS rate").Group(bucket).Order("timestamp ASC").Scan(&series.Points).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rate history for %s: %w", code, err)
+	}
+
+	// Statistics are computed over the raw rates, regardless of bucketing
+	err = s.db.Raw(`
+		SELECT
+			MIN(rate) AS min,
+			MAX(rate) AS max,
+			AVG(rate) AS average,
+			(ARRAY_AGG(rate ORDER BY fetched_at DESC))[1] / NULLIF((ARRAY_AGG(rate ORDER BY fetched_at ASC))[1], 0) * 100 - 100 AS percent_change
+		FROM exchange_rates
+		WHERE currency_code = ? AND fetched_at BETWEEN ? AND ?`, code, from, to).
+		Scan(&series.Summary).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize rate history for %s: %w", code, err)
+	}
+
+	return series, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"time"
+
+	"stage-2/config"
+)
+
+// Bounds of the multiplier used by the random and seeded estimators
+const (
+	minGDPMultiplier = 1000
+	maxGDPMultiplier = 2000
+)
+
+// GDPInput holds what a GDPEstimator knows about a country
+type GDPInput struct {
+	Name         string
+	Population   uint64
+	ExchangeRate *float64 // USD rate of the primary currency, if known
+	GDPPerCapita *float64 // In USD, if the provider supplies it
+	RefreshedAt  time.Time
+}
+
+// GDPEstimate is the result of a GDPEstimator, along with how it was produced
+type GDPEstimate struct {
+	Value     *float64
+	Estimator string
+	Params    map[string]interface{}
+}
+
+// GDPEstimator computes a country's estimated GDP.
+// Estimate returns an estimate with a nil Value if there isn't enough data.
+type GDPEstimator interface {
+	Estimate(in GDPInput) GDPEstimate
+}
+
+// NewGDPEstimator returns the GDPEstimator selected by the configuration
+func NewGDPEstimator(cfg config.GDPConfig) (GDPEstimator, error) {
+	if cfg.Estimator == config.GDPEstimatorPerCapita {
+		fallback, err := newMultiplierEstimator(cfg.Fallback, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &perCapitaEstimator{fallback: fallback}, nil
+	}
+	return newMultiplierEstimator(cfg.Estimator, cfg)
+}
+
+// newMultiplierEstimator returns one of the population × multiplier ÷ exchange_rate estimators
+func newMultiplierEstimator(name string, cfg config.GDPConfig) (GDPEstimator, error) {
+	switch name {
+	case config.GDPEstimatorSeeded:
+		return &seededEstimator{seed: cfg.Seed}, nil
+	case config.GDPEstimatorFixed:
+		return &fixedEstimator{multiplier: cfg.Multiplier}, nil
+	case config.GDPEstimatorRandom:
+		return &randomEstimator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown GDP estimator %q", name)
+	}
+}
+
+// multiplierEstimate computes population × multiplier ÷ exchange_rate
+func multiplierEstimate(name string, in GDPInput, multiplier float64, params map[string]interface{}) GDPEstimate {
+	if in.ExchangeRate == nil || *in.ExchangeRate == 0 {
+		return GDPEstimate{}
+	}
+	value := float64(in.Population) * multiplier / *in.ExchangeRate
+	params["multiplier"] = multiplier
+	return GDPEstimate{Value: &value, Estimator: name, Params: params}
+}
+
+// seededEstimator derives a multiplier between 1000 and 2000 from the country name and refresh date,
+// so repeated refreshes on the same day produce the same value
+type seededEstimator struct {
+	seed string
+}
+
+func (e *seededEstimator) Estimate(in GDPInput) GDPEstimate {
+	key := strings.ToLower(in.Name) + "|" + in.RefreshedAt.UTC().Format("2006-01-02")
+	if e.seed != "" {
+		key = e.seed + "|" + key
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	multiplier := float64(minGDPMultiplier + h.Sum64()%(maxGDPMultiplier-minGDPMultiplier+1))
+	return multiplierEstimate(config.GDPEstimatorSeeded, in, multiplier, map[string]interface{}{"seed": key})
+}
+
+// fixedEstimator uses the same multiplier for every country
+type fixedEstimator struct {
+	multiplier float64
+}
+
+func (e *fixedEstimator) Estimate(in GDPInput) GDPEstimate {
+	return multiplierEstimate(config.GDPEstimatorFixed, in, e.multiplier, map[string]interface{}{})
+}
+
+// randomEstimator draws a new multiplier between 1000 and 2000 on every refresh
+type randomEstimator struct{}
+
+func (e *randomEstimator) Estimate(in GDPInput) GDPEstimate {
+	multiplier := float64(rand.Intn(maxGDPMultiplier-minGDPMultiplier+1) + minGDPMultiplier)
+	return multiplierEstimate(config.GDPEstimatorRandom, in, multiplier, map[string]interface{}{})
+}
+
+// perCapitaEstimator computes population × GDP per capita when the provider has it,
+// and defers to fallback otherwise
+type perCapitaEstimator struct {
+	fallback GDPEstimator
+}
+
+func (e *perCapitaEstimator) Estimate(in GDPInput) GDPEstimate {
+	if in.GDPPerCapita == nil {
+		return e.fallback.Estimate(in)
+	}
+	value := float64(in.Population) * *in.GDPPerCapita
+	return GDPEstimate{
+		Value:     &value,
+		Estimator: config.GDPEstimatorPerCapita,
+		Params:    map[string]interface{}{"gdp_per_capita": *in.GDPPerCapita},
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+
+	"stage-2/config"
+	"stage-2/utils"
+)
+
+// RateProvider fetches exchange rates against USD, keyed by ISO currency code
+type RateProvider interface {
+	// Source names the provider in logs and error responses
+	Source() string
+	FetchRates() (map[string]float64, error)
+}
+
+// NewRateProvider returns the RateProvider selected by the configuration
+func NewRateProvider(cfg config.ProviderConfig, client *utils.HTTPClient) (RateProvider, error) {
+	switch cfg.RateProvider {
+	case config.ProviderERAPI:
+		return &ERAPIRateProvider{url: cfg.RatesURL, client: client}, nil
+	case config.ProviderFile:
+		return &FileRateProvider{path: cfg.RatesFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate provider %q", cfg.RateProvider)
+	}
+}
+
+// ratesResponse mirrors the open.er-api.com response format
+type ratesResponse struct {
+	Rates map[string]float64 `json:"rates"`
+}
+
+// ERAPIRateProvider fetches rates from open.er-api.com or any endpoint returning a USD-based "rates" object
+type ERAPIRateProvider struct {
+	url    string
+	client *utils.HTTPClient
+}
+
+// Source returns the host the provider fetches from
+func (p *ERAPIRateProvider) Source() string {
+	return hostOf(p.url)
+}
+
+// FetchRates fetches and decodes the latest rates
+func (p *ERAPIRateProvider) FetchRates() (map[string]float64, error) {
+	var response ratesResponse
+	if err := p.client.Get(p.url, &response); err != nil {
+		return nil, err
+	}
+	return response.Rates, nil
+}
+
+// FileRateProvider reads rates from a local JSON file in the open.er-api.com format
+type FileRateProvider struct {
+	path string
+}
+
+// Source returns the file the provider reads from
+func (p *FileRateProvider) Source() string {
+	return p.path
+}
+
+// FetchRates reads and decodes the rates from the file
+func (p *FileRateProvider) FetchRates() (map[string]float64, error) {
+	var response ratesResponse
+	if err := readJSONFile(p.path, &response); err != nil {
+		return nil, err
+	}
+	return response.Rates, nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"stage-2/models"
+
+	"gorm.io/gorm"
+)
+
+// progressFlushInterval is how many saved rows pass between job progress writes
+const progressFlushInterval = 25
+
+// RefreshJobService queues refresh jobs and runs them on a background worker
+type RefreshJobService struct {
+	db             *gorm.DB
+	countryService *CountryService
+	instanceID     string
+	wake           chan struct{}
+}
+
+// NewRefreshJobService creates a new RefreshJobService; instanceID is recorded on the jobs this replica runs
+func NewRefreshJobService(db *gorm.DB, cs *CountryService, instanceID string) *RefreshJobService {
+	return &RefreshJobService{
+		db:             db,
+		countryService: cs,
+		instanceID:     instanceID,
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Start recovers jobs interrupted by a previous shutdown and launches the background worker
+func (s *RefreshJobService) Start() error {
+	// Jobs this instance was running when it stopped can't be resumed
+	errMsg := "interrupted by server restart"
+	now := time.Now().UTC()
+	err := s.db.Model(&models.RefreshJob{}).
+		Where("state = ? AND instance_id = ?", models.RefreshJobRunning, s.instanceID).
+		Updates(map[string]interface{}{
+			"state":       models.RefreshJobFailed,
+			"error":       errMsg,
+			"finished_at": now,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to recover interrupted refresh jobs: %w", err)
+	}
+
+	go s.run()
+
+	// Pick up any jobs that were still queued
+	s.signal()
+	return nil
+}
+
+// EnqueueRefresh persists a new queued refresh job and wakes the worker.
+// actx is recorded on the job and attributed the refresh in the audit log.
+func (s *RefreshJobService) EnqueueRefresh(actx AuditContext) (*models.RefreshJob, error) {
+	job, err := s.enqueue(s.db, models.RefreshTriggerManual, actx)
+	if err != nil {
+		return nil, err
+	}
+	s.signal()
+	return job, nil
+}
+
+// enqueue persists a new queued refresh job through db, which may be a transaction.
+// The caller wakes the worker once the job is committed.
+func (s *RefreshJobService) enqueue(db *gorm.DB, trigger string, actx AuditContext) (*models.RefreshJob, error) {
+	job := models.RefreshJob{State: models.RefreshJobQueued, Trigger: trigger, RequestedBy: actx.Actor}
+	if actx.RequestID != "" {
+		job.RequestID = &actx.RequestID
+	}
+	if err := db.Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create refresh job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetJob fetches a refresh job by its ID
+func (s *RefreshJobService) GetJob(id uint) (*models.RefreshJob, error) {
+	var job models.RefreshJob
+	if err := s.db.First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Job not found
+		}
+		return nil, fmt.Errorf("failed to fetch refresh job %d: %w", id, err)
+	}
+	return &job, nil
+}
+
+// signal wakes the worker without blocking if a wake-up is already pending
+func (s *RefreshJobService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run drains queued jobs in creation order every time the worker is woken
+func (s *RefreshJobService) run() {
+	for range s.wake {
+		for {
+			var job models.RefreshJob
+			err := s.db.Where("state = ?", models.RefreshJobQueued).Order("id ASC").First(&job).Error
+			if err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					log.Printf("Error: Failed to fetch next refresh job: %v", err)
+				}
+				break
+			}
+			// Another replica may have claimed the job between the read and this update
+			claimed, err := s.claim(&job)
+			if err != nil {
+				log.Printf("Error: Failed to claim refresh job %d: %v", job.ID, err)
+				break
+			}
+			if claimed {
+				s.process(&job)
+			}
+		}
+	}
+}
+
+// claim marks a queued job as running, reporting false if it is no longer queued
+func (s *RefreshJobService) claim(job *models.RefreshJob) (bool, error) {
+	startedAt := time.Now().UTC()
+	res := s.db.Model(&models.RefreshJob{}).
+		Where("id = ? AND state = ?", job.ID, models.RefreshJobQueued).
+		Updates(map[string]interface{}{
+			"state":       models.RefreshJobRunning,
+			"started_at":  startedAt,
+			"instance_id": s.instanceID,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	job.State = models.RefreshJobRunning
+	job.StartedAt = &startedAt
+	job.InstanceID = s.instanceID
+	return res.RowsAffected == 1, nil
+}
+
+// process runs a single claimed refresh job and records its progress and outcome
+func (s *RefreshJobService) process(job *models.RefreshJob) {
+	log.Printf("Refresh job %d started", job.ID)
+
+	progress := func(phase string, processed, total int) {
+		if phase == job.Phase && processed != total && processed%progressFlushInterval != 0 {
+			return
+		}
+		job.Phase = phase
+		job.ProcessedCountries = processed
+		job.TotalCountries = total
+		err := s.db.Model(job).Updates(map[string]interface{}{
+			"phase":               phase,
+			"processed_countries": processed,
+			"total_countries":     total,
+		}).Error
+		if err != nil {
+			log.Printf("Warning: Failed to record progress for refresh job %d: %v", job.ID, err)
+		}
+	}
+
+	actx := AuditContext{Actor: job.RequestedBy}
+	if job.RequestID != nil {
+		actx.RequestID = *job.RequestID
+	}
+	result, err := s.countryService.RefreshCountries(RefreshOptions{
+		Trigger:  job.Trigger,
+		JobID:    &job.ID,
+		Progress: progress,
+		Audit:    actx,
+	})
+
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	if err != nil {
+		errMsg := err.Error()
+		job.State = models.RefreshJobFailed
+		job.Error = &errMsg
+		log.Printf("Refresh job %d failed: %v", job.ID, err)
+	} else {
+		job.State = models.RefreshJobSucceeded
+		job.TotalCountries = result.TotalCountries
+		job.ProcessedCountries = result.TotalCountries
+		job.RefreshRunID = &result.RunID
+		log.Printf("Refresh job %d succeeded with %d countries", job.ID, result.TotalCountries)
+	}
+
+	if err := s.db.Save(job).Error; err != nil {
+		log.Printf("Error: Failed to record outcome of refresh job %d: %v", job.ID, err)
+	}
+}
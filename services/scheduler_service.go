@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"stage-2/config"
+	"stage-2/models"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// refreshLockKey is the Postgres advisory lock key shared by all replicas for scheduled refreshes
+const refreshLockKey int64 = 0x636f756e74727900 // "country\x00"
+
+// schedule computes the next run time after a given time
+type schedule interface {
+	Next(time.Time) time.Time
+}
+
+// intervalSchedule fires on whole multiples of a fixed interval (see time.Truncate),
+// so every replica computes the same ticks regardless of when it started
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+// SchedulerService periodically queues country refreshes, electing one replica per tick via an advisory lock.
+// The refresh itself runs on the RefreshJobService worker, so it never overlaps a manually queued one.
+type SchedulerService struct {
+	db                *gorm.DB
+	refreshJobService *RefreshJobService
+	schedule          schedule
+	instanceID        string
+
+	mu      sync.RWMutex
+	nextRun *time.Time
+}
+
+// NewSchedulerService creates a new SchedulerService from the given configuration.
+// It returns a disabled scheduler if no schedule is configured.
+func NewSchedulerService(db *gorm.DB, js *RefreshJobService, cfg config.SchedulerConfig) (*SchedulerService, error) {
+	s := &SchedulerService{
+		db:                db,
+		refreshJobService: js,
+		instanceID:        cfg.InstanceID,
+	}
+
+	switch {
+	case cfg.CronExpr != "":
+		sched, err := cron.ParseStandard(cfg.CronExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REFRESH_CRON %q: %w", cfg.CronExpr, err)
+		}
+		s.schedule = sched
+	case cfg.Interval > 0:
+		s.schedule = intervalSchedule{interval: cfg.Interval}
+	}
+	return s, nil
+}
+
+// Start launches the scheduler loop if a schedule is configured
+func (s *SchedulerService) Start() {
+	if s.schedule == nil {
+		log.Println("No refresh schedule configured; scheduled refreshes are disabled.")
+		return
+	}
+	go s.run()
+}
+
+// NextRun returns the time of the next scheduled refresh, or nil if scheduling is disabled
+func (s *SchedulerService) NextRun() *time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextRun
+}
+
+// run sleeps until each scheduled tick and attempts to queue a refresh
+func (s *SchedulerService) run() {
+	for {
+		next := s.schedule.Next(time.Now().UTC()).UTC()
+		s.mu.Lock()
+		s.nextRun = &next
+		s.mu.Unlock()
+		log.Printf("Next scheduled refresh at %s", next.Format(time.RFC3339))
+
+		time.Sleep(time.Until(next))
+
+		if err := s.runTick(next); err != nil {
+			log.Printf("Error: Scheduled refresh for %s failed: %v", next.Format(time.RFC3339), err)
+		}
+	}
+}
+
+// runTick queues a refresh job for a single tick if this replica wins the advisory lock
+// and no other replica has already claimed the tick. The lock is only held while the tick
+// is claimed; the worker that takes the job fetches the data.
+func (s *SchedulerService) runTick(tick time.Time) error {
+	var job *models.RefreshJob
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// A transaction-level lock is released automatically on commit, rollback or a dropped connection
+		var acquired bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", refreshLockKey).Scan(&acquired).Error; err != nil {
+			return fmt.Errorf("failed to acquire refresh lock: %w", err)
+		}
+		if !acquired {
+			log.Printf("Scheduled refresh for %s is being claimed by another instance; skipping", tick.Format(time.RFC3339))
+			return nil
+		}
+
+		var status models.Status
+		if err := tx.Limit(1).Find(&status, 1).Error; err != nil {
+			return fmt.Errorf("failed to retrieve status: %w", err)
+		}
+		if status.LastScheduledRunAt != nil && !status.LastScheduledRunAt.Before(tick) {
+			log.Printf("Scheduled refresh for %s was already queued; skipping", tick.Format(time.RFC3339))
+			return nil
+		}
+
+		// The tick and its job are committed together, so a claimed tick always has a job
+		res := tx.Model(&models.Status{}).Where("id = ?", 1).Update("last_scheduled_run_at", tick)
+		if res.Error != nil {
+			return fmt.Errorf("failed to record scheduled run: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			// No refresh has run yet, so the status record doesn't exist
+			if err := tx.Create(&models.Status{ID: 1, LastScheduledRunAt: &tick}).Error; err != nil {
+				return fmt.Errorf("failed to record scheduled run: %w", err)
+			}
+		}
+		var err error
+		job, err = s.refreshJobService.enqueue(tx, models.RefreshTriggerScheduled, SchedulerAudit)
+		return err
+	})
+	if err != nil || job == nil {
+		return err
+	}
+
+	log.Printf("Queued scheduled refresh for %s as job %d on instance %s", tick.Format(time.RFC3339), job.ID, s.instanceID)
+	s.refreshJobService.signal()
+	return nil
+}
@@ -36,3 +36,55 @@ func (s *StatusService) GetStatus() (*models.Status, error) {
 	}
 	return &status, nil
 }
+
+// GetRefreshHistory retrieves the most recent refresh runs, newest first
+func (s *StatusService) GetRefreshHistory(limit int) ([]models.RefreshRun, error) {
+	var runs []models.RefreshRun
+	if err := s.db.Order("started_at DESC, id DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch refresh history: %w", err)
+	}
+	return runs, nil
+}
+
+// GetRefreshRun retrieves a single refresh run by its ID
+func (s *StatusService) GetRefreshRun(id uint) (*models.RefreshRun, error) {
+	var run models.RefreshRun
+	if err := s.db.First(&run, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Run not found
+		}
+		return nil, fmt.Errorf("failed to fetch refresh run %d: %w", id, err)
+	}
+	return &run, nil
+}
+
+// GetRefreshChanges retrieves the per-country changes recorded for a refresh run
+func (s *StatusService) GetRefreshChanges(runID uint) ([]models.CountryChange, error) {
+	var changes []models.CountryChange
+	if err := s.db.Where("refresh_run_id = ?", runID).Order("country_name ASC").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch changes for refresh run %d: %w", runID, err)
+	}
+	return changes, nil
+}
+
+// GetLatestRefreshRun retrieves the most recent successful refresh run, or nil if there is none
+func (s *StatusService) GetLatestRefreshRun() (*models.RefreshRun, error) {
+	var run models.RefreshRun
+	err := s.db.Where("outcome = ?", models.RefreshRunSucceeded).Order("started_at DESC, id DESC").First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // No successful run yet
+		}
+		return nil, fmt.Errorf("failed to fetch latest refresh run: %w", err)
+	}
+	return &run, nil
+}
+
+// CountStaleCountries counts countries flagged as missing from the upstream feed
+func (s *StatusService) CountStaleCountries() (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Country{}).Where("stale = ?", true).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count stale countries: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"stage-2/config"
+	"stage-2/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDatabaseEnv names the Postgres DSN that database tests and benchmarks run against.
+// They are skipped when it is unset.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// openTestDB connects to the test database and migrates a schema of its own, which is dropped when tb finishes,
+// so tests never touch existing tables
+func openTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		tb.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatalf("failed to connect to test database: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	// The search key function refers to public.unaccent, so the extensions must live in public
+	for _, stmt := range []string{
+		`CREATE EXTENSION IF NOT EXISTS unaccent SCHEMA public`,
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public`,
+		`CREATE SCHEMA ` + schema,
+	} {
+		if err := admin.Exec(stmt).Error; err != nil {
+			tb.Fatalf("failed to prepare test database: %v", err)
+		}
+	}
+	tb.Cleanup(func() {
+		if err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`).Error; err != nil {
+			tb.Errorf("failed to drop test schema %s: %v", schema, err)
+		}
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema+",public")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		tb.Fatalf("failed to connect to test schema: %v", err)
+	}
+	tb.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := config.ApplySchemaMigrations(db); err != nil {
+		tb.Fatalf("failed to migrate test schema: %v", err)
+	}
+	err = db.AutoMigrate(&models.Country{}, &models.CountryOverride{}, &models.Currency{}, &models.Status{}, &models.RefreshJob{}, &models.RefreshRun{}, &models.CountryChange{}, &models.CountrySnapshot{}, &models.ExchangeRate{}, &models.AuditEvent{})
+	if err != nil {
+		tb.Fatalf("failed to migrate test schema: %v", err)
+	}
+	if err := config.ApplySchemaExtras(db); err != nil {
+		tb.Fatalf("failed to migrate test schema: %v", err)
+	}
+	return db
+}
+
+// withSearchPath adds a search_path run-time parameter to a URL or keyword/value DSN
+func withSearchPath(dsn, searchPath string) string {
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + strings.ReplaceAll(searchPath, ",", "%2C")
+	}
+	return dsn + " search_path=" + searchPath
+}
@@ -41,3 +41,13 @@ func HandleNotFoundError(c *gin.Context, resource string) {
 func HandleServiceUnavailableError(c *gin.Context, details string) {
 	c.JSON(http.StatusServiceUnavailable, NewAPIError("External data source unavailable", details))
 }
+
+// HandleConflictError returns a 409 Conflict error response
+func HandleConflictError(c *gin.Context, msg string) {
+	c.JSON(http.StatusConflict, NewAPIError(msg, nil))
+}
+
+// HandleUnprocessableEntityError returns a 422 Unprocessable Entity error response
+func HandleUnprocessableEntityError(c *gin.Context, msg string, details interface{}) {
+	c.JSON(http.StatusUnprocessableEntity, NewAPIError(msg, details))
+}
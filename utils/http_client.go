@@ -1,16 +1,42 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
 	"time"
 )
 
+// HTTPMode controls whether the HTTPClient talks to the network, records responses, or replays them
+type HTTPMode string
+
+// Supported HTTP client modes
+const (
+	HTTPModeLive   HTTPMode = "live"   // Make real requests
+	HTTPModeRecord HTTPMode = "record" // Make real requests and save successfully decoded responses as fixtures
+	HTTPModeReplay HTTPMode = "replay" // Serve responses from fixtures without touching the network
+)
+
+// ErrNoRecordedResponse is returned in replay mode when a URL has no recorded fixture
+var ErrNoRecordedResponse = errors.New("no recorded response")
+
+// unsafeFixtureChars matches characters that are replaced when building fixture file names
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
 // HTTPClient is a wrapper for http.Client to make external API calls
 type HTTPClient struct {
-	client *http.Client
+	client      *http.Client
+	mode        HTTPMode
+	fixturesDir string
 }
 
 // NewHTTPClient creates a new HTTPClient with a default timeout
@@ -19,30 +45,115 @@ func NewHTTPClient() *HTTPClient {
 		client: &http.Client{
 			Timeout: 30 * time.Second, // Default timeout
 		},
+		mode: HTTPModeLive,
+	}
+}
+
+// NewFixtureHTTPClient creates an HTTPClient that records responses to, or replays them from, fixturesDir
+func NewFixtureHTTPClient(mode HTTPMode, fixturesDir string) (*HTTPClient, error) {
+	switch mode {
+	case HTTPModeLive, HTTPModeRecord, HTTPModeReplay:
+	default:
+		return nil, fmt.Errorf("unknown HTTP client mode %q", mode)
+	}
+	if mode != HTTPModeLive && fixturesDir == "" {
+		return nil, fmt.Errorf("a fixtures directory is required in %s mode", mode)
 	}
+
+	c := NewHTTPClient()
+	c.mode = mode
+	c.fixturesDir = fixturesDir
+	return c, nil
 }
 
 // Get performs a GET request to the specified URL and decodes the JSON response into the target interface
 func (c *HTTPClient) Get(url string, target interface{}) error {
+	var (
+		body []byte
+		err  error
+	)
+	if c.mode == HTTPModeReplay {
+		body, err = c.replay(url)
+	} else {
+		body, err = c.fetch(url)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", url, err)
+	}
+
+	// Only responses the caller could use are recorded, so a bad upstream response never becomes a fixture
+	if c.mode == HTTPModeRecord {
+		if err := c.record(url, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetch performs the GET request and returns the body of a successful response
+func (c *HTTPClient) fetch(url string) ([]byte, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to make HTTP request to %s: %w", url, err)
+		return nil, fmt.Errorf("failed to make HTTP request to %s: %w", url, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request to %s failed with status %d: %s", url, resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("API request to %s failed with status %d: %s", url, resp.StatusCode, string(bodyBytes))
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
-		return fmt.Errorf("failed to decode response from %s: %w", url, err)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
 	}
+	return body, nil
+}
 
+// record saves a response body as the fixture for url
+func (c *HTTPClient) record(url string, body []byte) error {
+	if err := os.MkdirAll(c.fixturesDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+	path := c.FixturePath(url)
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return fmt.Errorf("failed to record response from %s: %w", url, err)
+	}
 	return nil
 }
+
+// replay returns the recorded response body for url
+func (c *HTTPClient) replay(url string) ([]byte, error) {
+	path := c.FixturePath(url)
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for %s: expected fixture %s (run in record mode to create it)", ErrNoRecordedResponse, url, path)
+		}
+		return nil, fmt.Errorf("failed to read fixture for %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// FixturePath returns the file a response from rawURL is recorded to and replayed from.
+// The name keeps the host and path readable and adds a hash of the full URL so query strings don't collide.
+func (c *HTTPClient) FixturePath(rawURL string) string {
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	name = unsafeFixtureChars.ReplaceAllString(name, "_")
+
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.fixturesDir, fmt.Sprintf("%s_%s.json", name, hex.EncodeToString(sum[:])[:12]))
+}
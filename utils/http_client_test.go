@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNewFixtureHTTPClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        HTTPMode
+		fixturesDir string
+		wantErr     bool
+	}{
+		{"live without fixtures", HTTPModeLive, "", false},
+		{"record", HTTPModeRecord, "fixtures", false},
+		{"replay", HTTPModeReplay, "fixtures", false},
+		{"record without fixtures", HTTPModeRecord, "", true},
+		{"replay without fixtures", HTTPModeReplay, "", true},
+		{"unknown mode", HTTPMode("mock"), "fixtures", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFixtureHTTPClient(tt.mode, tt.fixturesDir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFixtureHTTPClient(%q, %q) error = %v, want error %t", tt.mode, tt.fixturesDir, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPClientRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"rates": {"EUR": 0.861}}`))
+		case "/malformed":
+			w.Write([]byte(`<html>maintenance</html>`))
+		default:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewFixtureHTTPClient(HTTPModeRecord, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := NewFixtureHTTPClient(HTTPModeReplay, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path         string
+		wantRecorded bool
+	}{
+		{"/ok", true},
+		{"/malformed", false},
+		{"/unavailable", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			url := server.URL + tt.path
+			var target struct {
+				Rates map[string]float64 `json:"rates"`
+			}
+			err := recorder.Get(url, &target)
+			if (err == nil) != tt.wantRecorded {
+				t.Fatalf("record Get(%s) error = %v, want success %t", tt.path, err, tt.wantRecorded)
+			}
+
+			_, statErr := os.Stat(recorder.FixturePath(url))
+			if recorded := statErr == nil; recorded != tt.wantRecorded {
+				t.Fatalf("fixture for %s recorded = %t, want %t", tt.path, recorded, tt.wantRecorded)
+			}
+
+			var replayed struct {
+				Rates map[string]float64 `json:"rates"`
+			}
+			err = replayer.Get(url, &replayed)
+			if !tt.wantRecorded {
+				if !errors.Is(err, ErrNoRecordedResponse) {
+					t.Fatalf("replay Get(%s) error = %v, want ErrNoRecordedResponse", tt.path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("replay Get(%s) error = %v", tt.path, err)
+			}
+			if replayed.Rates["EUR"] != 0.861 {
+				t.Errorf("replayed rates = %v, want EUR 0.861", replayed.Rates)
+			}
+		})
+	}
+}
+
+func TestFixturePathDistinguishesQueries(t *testing.T) {
+	c := &HTTPClient{fixturesDir: "fixtures"}
+	a := c.FixturePath("https://restcountries.com/v2/all?fields=name")
+	b := c.FixturePath("https://restcountries.com/v2/all?fields=name,capital")
+	if a == b {
+		t.Errorf("FixturePath gave %s for URLs with different queries", a)
+	}
+	if a != c.FixturePath("https://restcountries.com/v2/all?fields=name") {
+		t.Errorf("FixturePath is not stable for the same URL")
+	}
+}
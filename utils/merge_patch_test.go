@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	// The examples of RFC 7386, appendix A, plus the cases the country endpoints rely on
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replace member", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes member", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"null removes only that member", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"array replaced", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"value replaced by array", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"nested merge", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"array of objects replaced", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"arrays replace whole", `["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{"object replaces array", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"null patch", `{"a":"foo"}`, `null`, `null`},
+		{"string patch", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"null member kept in document", `{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{"patch into non-object", `[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{"nested null in new member", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{"empty patch", `{"name":"Nigeria","capital":"Abuja"}`, `{}`, `{"name":"Nigeria","capital":"Abuja"}`},
+		{
+			"country fields",
+			`{"name":"Nigeria","capital":"Lagos","population":206139589,"flag_url":"https://flagcdn.com/ng.svg"}`,
+			`{"capital":"Abuja","flag_url":null,"exchange_rate":1600.23}`,
+			`{"name":"Nigeria","capital":"Abuja","population":206139589,"exchange_rate":1600.23}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyMergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("ApplyMergePatch() error = %v", err)
+			}
+			var gotValue, wantValue interface{}
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatalf("ApplyMergePatch() returned invalid JSON %s: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Errorf("ApplyMergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatchInvalidJSON(t *testing.T) {
+	if _, err := ApplyMergePatch([]byte(`{"a":`), []byte(`{}`)); err == nil {
+		t.Error("ApplyMergePatch() with an invalid document succeeded")
+	}
+	if _, err := ApplyMergePatch([]byte(`{}`), []byte(`{"a"}`)); err == nil {
+		t.Error("ApplyMergePatch() with an invalid patch succeeded")
+	}
+}